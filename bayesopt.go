@@ -1,6 +1,14 @@
 package bayesopt
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -10,6 +18,11 @@ import (
 	"github.com/d4l3k/go-bayesopt/gp"
 )
 
+// corpusFormat is the header identifying the text encoding Save/Load and
+// WithCorpusDir use to persist an optimizer's observations, versioned in
+// case the format needs to change in a backwards-incompatible way.
+const corpusFormat = "go-bayesopt corpus v1"
+
 const (
 	// DefaultRounds is the default number of rounds to run.
 	DefaultRounds = 20
@@ -17,6 +30,9 @@ const (
 	DefaultRandomRounds = 5
 	// DefaultMinimize is the default value of minimize.
 	DefaultMinimize = true
+	// DefaultBatchSize is the default number of candidates proposed per
+	// round.
+	DefaultBatchSize = 1
 
 	NumRandPoints = 100000
 	NumGradPoints = 256
@@ -26,7 +42,31 @@ var (
 	// DefaultExploration uses UCB with 95 confidence interval.
 	DefaultExploration = UCB{Kappa: 1.96}
 	// DefaultBarrierFunc sets the default barrier function to use.
-	DefaultBarrierFunc = LogBarrier{}
+	DefaultBarrierFunc BarrierFunc = LogBarrier
+	// DefaultBatchStrategy fakes observations using the GP's posterior mean.
+	DefaultBatchStrategy = KrigingBeliever
+	// DefaultHyperTrainNoise is the starting noise variance WithHyperTraining
+	// seeds the GP with when none has been set, since training log(noise)
+	// from zero is degenerate.
+	DefaultHyperTrainNoise = 1e-2
+	// DefaultSeed is the seed used to drive an Optimizer's sampling when
+	// WithRand isn't passed, so that Optimize is bit-for-bit reproducible by
+	// default rather than just when explicitly configured to be.
+	DefaultSeed int64 = 1
+)
+
+// BatchStrategy picks the fake y value assigned to a just-proposed candidate
+// before the next candidate in the same batch is proposed, so that
+// candidates within a batch don't collapse onto the same point.
+type BatchStrategy int
+
+const (
+	// KrigingBeliever fakes the observation using the GP's posterior mean at
+	// the candidate.
+	KrigingBeliever BatchStrategy = iota
+	// ConstantLiar fakes the observation using a fixed constant: the best
+	// value observed so far.
+	ConstantLiar
 )
 
 // Optimizer is a blackbox gaussian process optimizer.
@@ -39,6 +79,18 @@ type Optimizer struct {
 		exploration                 Exploration
 		minimize                    bool
 		barrierFunc                 BarrierFunc
+		batchSize                   int
+		batchStrategy               BatchStrategy
+		pending                     []map[Param]float64
+		innerOptimizer              InnerOptimizer
+		hyperTrainEvery             int
+		hyperTrainNoise             bool
+		seed                        int64
+		rng                         *rand.Rand
+		localOptimizer              LocalOptimizer
+		gradPoints                  int
+		corpusDir                   string
+		loadingCorpus               bool
 
 		running        bool
 		explorationErr error
@@ -92,6 +144,123 @@ func WithBarrierFunc(bf BarrierFunc) OptimizerOption {
 	}
 }
 
+// WithBatchSize sets the number of candidates proposed per round, letting
+// the objective function be evaluated for a full batch in parallel. Batches
+// larger than one are proposed using BatchStrategy to fake intermediate
+// observations so that candidates don't collapse onto the same point.
+func WithBatchSize(n int) OptimizerOption {
+	return func(o *Optimizer) {
+		o.mu.batchSize = n
+	}
+}
+
+// WithBatchStrategy sets the strategy used to fake observations for
+// in-progress batch candidates. Only relevant when WithBatchSize is greater
+// than one.
+func WithBatchStrategy(s BatchStrategy) OptimizerOption {
+	return func(o *Optimizer) {
+		o.mu.batchStrategy = s
+	}
+}
+
+// WithInnerOptimizer sets the optimizer used to maximize the acquisition
+// function each round. If unset, acquisitions are maximized via random
+// sampling followed by bounded local refinement with the LocalOptimizer's
+// Method (L-BFGS unless WithLocalMethod sets another).
+func WithInnerOptimizer(o InnerOptimizer) OptimizerOption {
+	return func(opt *Optimizer) {
+		opt.mu.innerOptimizer = o
+	}
+}
+
+// LocalOptimizer configures the gonum optimize.Method selectCandidate runs
+// to refine each candidate found by the default random-sampling global
+// search (see WithInnerOptimizer for replacing that pipeline entirely).
+// Method is always run wrapped in a BoundsMethod, so it never has to be
+// bounds-aware itself; Settings lets callers tune convergence thresholds
+// such as optimize.Settings.GradientThreshold or a Method's own
+// GradStopThreshold field (BFGS, CG, LBFGS, Newton, GradientDescent all
+// expose one).
+type LocalOptimizer struct {
+	Method   optimize.Method
+	Settings *optimize.Settings
+}
+
+// WithLocalMethod sets the gonum optimize.Method used to refine acquisition
+// candidates, e.g. &optimize.CG{} or &optimize.NelderMead{}. Defaults to
+// &optimize.LBFGS{}.
+func WithLocalMethod(method optimize.Method) OptimizerOption {
+	return func(o *Optimizer) {
+		o.mu.localOptimizer.Method = method
+	}
+}
+
+// WithLocalSettings sets the optimize.Settings used for the local refinement
+// of acquisition candidates, letting callers configure convergence
+// thresholds such as GradientThreshold or FunctionConverge.
+func WithLocalSettings(settings *optimize.Settings) OptimizerOption {
+	return func(o *Optimizer) {
+		o.mu.localOptimizer.Settings = settings
+	}
+}
+
+// WithGradPoints overrides the number of random restarts selectCandidate
+// runs the local method from after the initial random-sample-then-local-
+// method pass. Defaults to NumGradPoints.
+func WithGradPoints(n int) OptimizerOption {
+	return func(o *Optimizer) {
+		o.mu.gradPoints = n
+	}
+}
+
+// WithHyperTraining enables periodically refitting the GP's kernel
+// hyperparameters to the logged observations by maximizing the log marginal
+// likelihood (see gp.GP.LearnHyperparameters): whenever a Log call brings the
+// number of logged observations to a multiple of every, the hyperparameters
+// are refit.
+// If trainNoise is true, the observation noise variance is fit alongside
+// them instead of being held fixed. Training failures are non-fatal: the GP
+// falls back to keeping its current hyperparameters and the outer
+// optimization loop continues.
+//
+// This requires an ARD covariance function (see gp.MaternARD,
+// gp.SquaredExponentialARD); if the covariance hasn't been set some other
+// way, New defaults to a unit-hyperparameter gp.MaternARD.
+func WithHyperTraining(every int, trainNoise bool) OptimizerOption {
+	return func(o *Optimizer) {
+		o.mu.hyperTrainEvery = every
+		o.mu.hyperTrainNoise = trainNoise
+	}
+}
+
+// WithRand sets the seed used to drive all of the optimizer's random
+// sampling: initial random rounds, the global search over the acquisition
+// function, its random-restart gradient descent, WithInnerOptimizer's
+// population sampling, WithHyperTraining's restart jitter, and
+// IntegratedAcquisition's hyperparameter slice sampling. Two Optimizers constructed with the same seed propose the exact
+// same points given the same sequence of observations, which DefaultSeed
+// already guarantees by default; WithRand only matters for running
+// independent replicates.
+func WithRand(seed int64) OptimizerOption {
+	return func(o *Optimizer) {
+		o.mu.seed = seed
+	}
+}
+
+// WithCorpusDir makes the optimizer persist its observations to a corpus
+// file under dir in the same format Save/Load use, so a long-running tuning
+// job survives a process restart: New loads any corpus already at that path
+// to warm-start the GP before the first round runs, and every subsequent
+// Log call rewrites the file with the observations logged so far. Rewriting
+// the whole file rather than truly streaming appends keeps a crash mid-write
+// from corrupting a previous run's corpus, since the new file is written to
+// a temp path and renamed into place only once it's complete.
+func WithCorpusDir(dir string) OptimizerOption {
+	return func(o *Optimizer) {
+		o.mu.corpusDir = dir
+	}
+}
+
 // New creates a new optimizer with the specified optimizable parameters and
 // options.
 func New(params []Param, opts ...OptimizerOption) *Optimizer {
@@ -105,16 +274,77 @@ func New(params []Param, opts ...OptimizerOption) *Optimizer {
 	o.mu.exploration = DefaultExploration
 	o.mu.minimize = DefaultMinimize
 	o.mu.barrierFunc = DefaultBarrierFunc
+	o.mu.batchSize = DefaultBatchSize
+	o.mu.batchStrategy = DefaultBatchStrategy
+	o.mu.seed = DefaultSeed
+	o.mu.localOptimizer = LocalOptimizer{Method: &optimize.LBFGS{}}
+	o.mu.gradPoints = NumGradPoints
 
 	o.updateNames("")
 
 	for _, opt := range opts {
 		opt(o)
 	}
+	o.mu.rng = rand.New(rand.NewSource(o.mu.seed))
+
+	// IntegratedAcquisition.Rand defaults to nil; wire it to the Optimizer's
+	// own seeded rng unless the caller already set one, so WithRand's
+	// reproducibility guarantee extends to its hyperparameter sampling too.
+	if ia, ok := o.mu.exploration.(IntegratedAcquisition); ok && ia.Rand == nil {
+		ia.Rand = o.mu.rng
+		o.mu.exploration = ia
+	}
+
+	// WithHyperTraining needs an ARD covariance to fit; if the caller didn't
+	// set one some other way, fall back to a unit-hyperparameter MaternARD
+	// sized to the search space.
+	if o.mu.hyperTrainEvery > 0 {
+		if _, ok := o.mu.gp.Cov().(gp.ARDCov); !ok {
+			lengthscales := make([]float64, paramDims(params))
+			for i := range lengthscales {
+				lengthscales[i] = 1
+			}
+			inputNames := make([]string, len(lengthscales))
+			for i := range inputNames {
+				inputNames[i] = o.mu.gp.Name(i)
+			}
+			outputName := o.mu.gp.OutputName()
+			noise := o.mu.gp.Noise()
+			if noise == 0 {
+				noise = DefaultHyperTrainNoise
+			}
+
+			o.mu.gp = gp.New(gp.MaternARD{Lengthscales: lengthscales, SignalVar: 1}, noise)
+			o.mu.gp.SetNames(inputNames, outputName)
+		}
+	}
+
+	// Warm-start from a prior run's corpus, if WithCorpusDir was set and one
+	// exists. Errors here surface through ExplorationErr, same as exploration
+	// failures during optimization, since New doesn't otherwise return one.
+	if o.mu.corpusDir != "" {
+		if err := os.MkdirAll(o.mu.corpusDir, 0o755); err != nil {
+			o.mu.explorationErr = errors.Wrap(err, "failed to create corpus dir")
+		} else if f, err := os.Open(corpusPath(o.mu.corpusDir)); err == nil {
+			err := o.Load(f)
+			f.Close()
+			if err != nil {
+				o.mu.explorationErr = errors.Wrap(err, "failed to load corpus")
+			}
+		} else if !os.IsNotExist(err) {
+			o.mu.explorationErr = errors.Wrap(err, "failed to open corpus")
+		}
+	}
 
 	return o
 }
 
+// corpusPath returns the path WithCorpusDir reads and writes the corpus
+// file at within dir.
+func corpusPath(dir string) string {
+	return filepath.Join(dir, "corpus")
+}
+
 // updateNames sets the gaussian process names.
 func (o *Optimizer) updateNames(outputName string) {
 	o.mu.Lock()
@@ -122,6 +352,12 @@ func (o *Optimizer) updateNames(outputName string) {
 
 	var inputNames []string
 	for _, p := range o.mu.params {
+		if md, ok := p.(MultiDimParam); ok {
+			for i := 0; i < md.Dims(); i++ {
+				inputNames = append(inputNames, fmt.Sprintf("%s[%d]", p.GetName(), i))
+			}
+			continue
+		}
 		inputNames = append(inputNames, p.GetName())
 	}
 	o.mu.gp.SetNames(inputNames, outputName)
@@ -136,22 +372,55 @@ func (o *Optimizer) GP() *gp.GP {
 	return o.mu.gp
 }
 
-func sampleParams(params []Param) []float64 {
-	x := make([]float64, len(params))
-	for i, p := range params {
-		x[i] = p.Sample()
+// Seed returns the seed driving the optimizer's random sampling, as set by
+// WithRand or defaulted to DefaultSeed.
+func (o *Optimizer) Seed() int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.mu.seed
+}
+
+// sampleParamVals samples a value for each param, keyed by Param the way
+// Optimize reports results. ConditionalParams are resolved against their
+// already-sampled Parent, which must precede them in params.
+func sampleParamVals(params []Param, rng *rand.Rand) map[Param]float64 {
+	vals := map[Param]float64{}
+	for _, p := range params {
+		if cp, ok := p.(*ConditionalParam); ok && !cp.Active(vals[cp.Parent]) {
+			vals[p] = cp.Default
+			continue
+		}
+		vals[p] = sample(p, rng)
 	}
-	return x
+	return vals
 }
 
-func sampleParamsMap(params []Param) map[Param]float64 {
-	x := map[Param]float64{}
-	for i, v := range sampleParams(params) {
-		x[params[i]] = v
+// encodeParamVals converts a parameter value map into the GP's raw input
+// vector, one-hot expanding MultiDimParams.
+func encodeParamVals(params []Param, vals map[Param]float64) []float64 {
+	var x []float64
+	for _, p := range params {
+		v := vals[p]
+		if md, ok := p.(MultiDimParam); ok {
+			x = append(x, md.Encode(v)...)
+		} else {
+			x = append(x, v)
+		}
 	}
 	return x
 }
 
+// sampleParams returns a random point within the bounds of params, encoded as
+// the GP's raw input vector.
+func sampleParams(params []Param, rng *rand.Rand) []float64 {
+	return encodeParamVals(params, sampleParamVals(params, rng))
+}
+
+func sampleParamsMap(params []Param, rng *rand.Rand) map[Param]float64 {
+	return sampleParamVals(params, rng)
+}
+
 type randerFunc func([]float64) []float64
 
 func (f randerFunc) Rand(x []float64) []float64 {
@@ -183,25 +452,13 @@ func isFatalErr(err error) bool {
 	}
 }
 
-// Next returns the next best x values to explore. If more than rounds have
-// elapsed, nil is returned. If parallel is true, that round can happen in
-// parallel to other rounds.
-func (o *Optimizer) Next() (x map[Param]float64, parallel bool, err error) {
-	o.mu.Lock()
-	defer o.mu.Unlock()
-
-	// Return if we've exceeded max # of rounds, or if there was an error while
-	// doing exploration which is likely caused by numerical precision errors.
-	if o.mu.round >= o.mu.rounds || o.mu.explorationErr != nil {
-		return nil, false, nil
-	}
-
-	// If we don't have enough random rounds, run more.
-	if o.mu.round < o.mu.randomRounds {
-		x = sampleParamsMap(o.mu.params)
-		o.mu.round += 1
-		// Don't return parallel on the last random round.
-		return x, o.mu.round != o.mu.randomRounds, nil
+// selectCandidate maximizes the exploration function over the parameter
+// bounds and returns the best x found. On any exploration/gradient error it
+// records o.mu.explorationErr and returns that error. Callers must hold
+// o.mu.
+func (o *Optimizer) selectCandidate() ([]float64, error) {
+	if o.mu.innerOptimizer != nil {
+		return o.selectCandidateInner(o.mu.innerOptimizer)
 	}
 
 	var fErr error
@@ -219,24 +476,39 @@ func (o *Optimizer) Next() (x map[Param]float64, parallel bool, err error) {
 	problem := optimize.Problem{
 		Func: f,
 		Grad: func(grad, x []float64) {
-			g, err := o.mu.gp.Gradient(x)
+			var g []float64
+			var err error
+			if ge, ok := o.mu.exploration.(GradientExploration); ok {
+				// The exploration function knows how to differentiate its
+				// own Estimate; use that instead of the GP mean gradient
+				// fallback below, which ignores the uncertainty term that
+				// acquisition functions like EI/PI depend on.
+				g, err = ge.Gradient(o.mu.gp, o.mu.minimize, x)
+			} else {
+				g, err = o.mu.gp.Gradient(x)
+			}
 			if err != nil {
 				fErr = errors.Wrap(err, "gradient error")
 			}
+			if !o.mu.minimize {
+				for i := range g {
+					g[i] = -g[i]
+				}
+			}
 			copy(grad, g)
 		},
 	}
 
 	// Randomly query a bunch of points to get a good estimate of maximum.
-	result, err := optimize.Global(problem, len(o.mu.params), &optimize.Settings{
+	result, err := optimize.Minimize(problem, sampleParams(o.mu.params, o.mu.rng), &optimize.Settings{
 		FuncEvaluations: NumRandPoints,
 	}, &optimize.GuessAndCheck{
 		Rander: randerFunc(func(x []float64) []float64 {
-			return sampleParams(o.mu.params)
+			return sampleParams(o.mu.params, o.mu.rng)
 		}),
 	})
 	if err != nil {
-		return nil, false, errors.Wrapf(err, "random sample failed")
+		return nil, errors.Wrapf(err, "random sample failed")
 	}
 	if fErr != nil {
 		o.mu.explorationErr = fErr
@@ -245,14 +517,12 @@ func (o *Optimizer) Next() (x map[Param]float64, parallel bool, err error) {
 	minX := result.X
 
 	// Run gradient descent on the best point.
-	method := optimize.LBFGS{}
 	grad := BoundsMethod{
-		Method: &method,
+		Method: o.mu.localOptimizer.Method,
 		Bounds: o.mu.params,
 	}
-	// TODO(d4l3k): Bounded line searcher.
 	{
-		result, err := optimize.Local(problem, minX, nil, grad)
+		result, err := optimize.Minimize(problem, minX, o.mu.localOptimizer.Settings, grad)
 		if isFatalErr(err) {
 			o.mu.explorationErr = errors.Wrapf(err, "random sample optimize failed")
 		}
@@ -266,9 +536,9 @@ func (o *Optimizer) Next() (x map[Param]float64, parallel bool, err error) {
 	}
 
 	// Attempt to use gradient descent on random points.
-	for i := 0; i < NumGradPoints; i++ {
-		x := sampleParams(o.mu.params)
-		result, err := optimize.Local(problem, x, nil, grad)
+	for i := 0; i < o.mu.gradPoints; i++ {
+		x := sampleParams(o.mu.params, o.mu.rng)
+		result, err := optimize.Minimize(problem, x, o.mu.localOptimizer.Settings, grad)
 		if isFatalErr(err) {
 			o.mu.explorationErr = errors.Wrapf(err, "gradient descent failed: i %d, x %+v, result%+v", i, x, result)
 		}
@@ -282,16 +552,152 @@ func (o *Optimizer) Next() (x map[Param]float64, parallel bool, err error) {
 	}
 
 	if o.mu.explorationErr != nil {
-		return nil, false, nil
+		return nil, o.mu.explorationErr
+	}
+	return minX, nil
+}
+
+// selectCandidateInner maximizes the exploration function using inner
+// instead of the default random-sample-then-L-BFGS pipeline. Callers must
+// hold o.mu.
+func (o *Optimizer) selectCandidateInner(inner InnerOptimizer) ([]float64, error) {
+	var fErr error
+	f := func(x []float64) float64 {
+		v, err := o.mu.exploration.Estimate(o.mu.gp, o.mu.minimize, x)
+		if err != nil {
+			fErr = errors.Wrap(err, "exploration error")
+		}
+		if o.mu.minimize {
+			return -v
+		}
+		return v
+	}
+
+	maxX, err := inner.Maximize(o.mu.rng, f, o.mu.params)
+	if err != nil {
+		o.mu.explorationErr = errors.Wrap(err, "inner optimizer failed")
+		return nil, o.mu.explorationErr
 	}
+	if fErr != nil {
+		o.mu.explorationErr = fErr
+		return nil, o.mu.explorationErr
+	}
+	return maxX, nil
+}
 
+// fakeObservation returns the y value to pretend was observed at x while
+// picking the rest of a batch, per o.mu.batchStrategy. Callers must hold
+// o.mu.
+func (o *Optimizer) fakeObservation(x []float64) (float64, error) {
+	if o.mu.batchStrategy == ConstantLiar {
+		if o.mu.minimize {
+			_, y := o.mu.gp.Minimum()
+			return y, nil
+		}
+		_, y := o.mu.gp.Maximum()
+		return y, nil
+	}
+	mean, _, err := o.mu.gp.Estimate(x)
+	return mean, err
+}
+
+// paramMap converts a raw GP input vector into the map[Param]float64 form
+// used by the public API, collapsing one-hot encoded MultiDimParams back to
+// a choice index, rounding Rounder params (e.g. IntParam) to the nearest
+// valid value, and replacing inactive ConditionalParams with their Default.
+func (o *Optimizer) paramMap(x []float64) map[Param]float64 {
 	m := map[Param]float64{}
-	for i, x := range minX {
-		m[o.mu.params[i]] = x
+	i := 0
+	for _, p := range o.mu.params {
+		if md, ok := p.(MultiDimParam); ok {
+			n := md.Dims()
+			m[p] = md.Decode(x[i : i+n])
+			i += n
+			continue
+		}
+		v := x[i]
+		if r, ok := p.(Rounder); ok {
+			v = r.Round(v)
+		}
+		if cp, ok := p.(*ConditionalParam); ok && !cp.Active(m[cp.Parent]) {
+			v = cp.Default
+		}
+		m[p] = v
+		i++
 	}
+	return m
+}
+
+// Next returns the next best x values to explore. If more than rounds have
+// elapsed, nil is returned. If parallel is true, that round can happen in
+// parallel to other rounds.
+func (o *Optimizer) Next() (x map[Param]float64, parallel bool, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Dispense the rest of an in-progress batch before proposing a new one.
+	if len(o.mu.pending) > 0 {
+		x = o.mu.pending[0]
+		o.mu.pending = o.mu.pending[1:]
+		o.mu.round += 1
+		return x, len(o.mu.pending) > 0, nil
+	}
+
+	// Return if we've exceeded max # of rounds, or if there was an error while
+	// doing exploration which is likely caused by numerical precision errors.
+	if o.mu.round >= o.mu.rounds || o.mu.explorationErr != nil {
+		return nil, false, nil
+	}
+
+	// If we don't have enough random rounds, run more.
+	if o.mu.round < o.mu.randomRounds {
+		x = sampleParamsMap(o.mu.params, o.mu.rng)
+		o.mu.round += 1
+		// Don't return parallel on the last random round.
+		return x, o.mu.round != o.mu.randomRounds, nil
+	}
+
+	batchSize := o.mu.batchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	n := o.mu.gp.Len()
+
+	var candidates [][]float64
+	for i := 0; i < batchSize; i++ {
+		minX, err := o.selectCandidate()
+		if err != nil {
+			break
+		}
+		candidates = append(candidates, minX)
+
+		// Kriging-Believer/Constant-Liar: temporarily add a fake observation
+		// so the next candidate in the batch doesn't pick the same point.
+		if i < batchSize-1 {
+			y, err := o.fakeObservation(minX)
+			if err != nil {
+				o.mu.explorationErr = errors.Wrap(err, "fake observation error")
+				break
+			}
+			o.mu.gp.Add(minX, y)
+		}
+	}
+	// Roll back any fake observations; the real ones are added by Log once
+	// the objective has actually been evaluated.
+	o.mu.gp.Truncate(n)
+
+	if o.mu.explorationErr != nil || len(candidates) == 0 {
+		return nil, false, nil
+	}
+
+	maps := make([]map[Param]float64, len(candidates))
+	for i, c := range candidates {
+		maps[i] = o.paramMap(c)
+	}
+	o.mu.pending = maps[1:]
 
 	o.mu.round += 1
-	return m, false, nil
+	return maps[0], len(o.mu.pending) > 0, nil
 }
 
 func (o *Optimizer) ExplorationErr() error {
@@ -305,11 +711,196 @@ func (o *Optimizer) Log(x map[Param]float64, y float64) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
-	var xa []float64
+	o.mu.gp.Add(encodeParamVals(o.mu.params, x), y)
+	o.trainGP()
+
+	// Load replays its own observations through Log while o.mu.round is
+	// still the pre-load value; persisting on every one of those calls
+	// would write a corpus file whose round header doesn't match what Load
+	// restores once the whole replay finishes. Load persists once itself
+	// afterwards instead, see loadingCorpus below.
+	if o.mu.corpusDir != "" && !o.mu.loadingCorpus {
+		if err := o.persistCorpusLocked(); err != nil {
+			o.mu.explorationErr = errors.Wrap(err, "failed to persist corpus")
+		}
+	}
+}
+
+// persistCorpusLocked rewrites the corpus file under o.mu.corpusDir with the
+// optimizer's current observations, via a temp file and rename so a reader
+// (or a crash) never sees a partially written file. Callers must hold o.mu.
+func (o *Optimizer) persistCorpusLocked() error {
+	path := corpusPath(o.mu.corpusDir)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return errors.Wrap(err, "failed to create corpus temp file")
+	}
+	if err := o.saveLocked(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed to close corpus temp file")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrap(err, "failed to rename corpus temp file")
+	}
+	return nil
+}
+
+// Save writes the optimizer's parameter schema and logged observations to w,
+// in a plain-text format similar to Go's fuzzing corpus encoding: a header
+// naming the schema version and the number of rounds run, one
+// `param "name" min max` line per parameter, and then one "v1 v2 ... -> y" line per observation.
+// Load reads the format back, replaying the observations via Log.
+func (o *Optimizer) Save(w io.Writer) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.saveLocked(w)
+}
+
+// saveLocked is Save without the locking, so Log and persistCorpusLocked can
+// call it while already holding o.mu. Callers must hold o.mu.
+func (o *Optimizer) saveLocked(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	if _, err := fmt.Fprintf(bw, "%s\nround %d\n", corpusFormat, o.mu.round); err != nil {
+		return errors.Wrap(err, "failed to write corpus header")
+	}
 	for _, p := range o.mu.params {
-		xa = append(xa, x[p])
+		if _, err := fmt.Fprintf(bw, "param %q %s %s\n",
+			p.GetName(),
+			strconv.FormatFloat(p.GetMin(), 'g', -1, 64),
+			strconv.FormatFloat(p.GetMax(), 'g', -1, 64),
+		); err != nil {
+			return errors.Wrap(err, "failed to write corpus param")
+		}
+	}
+
+	inputs, outputs := o.mu.gp.RawData()
+	vals := make([]string, len(o.mu.params))
+	for i, x := range inputs {
+		paramVals := o.paramMap(x)
+		for j, p := range o.mu.params {
+			vals[j] = strconv.FormatFloat(paramVals[p], 'g', -1, 64)
+		}
+		if _, err := fmt.Fprintf(bw, "%s -> %s\n",
+			strings.Join(vals, " "),
+			strconv.FormatFloat(outputs[i], 'g', -1, 64),
+		); err != nil {
+			return errors.Wrap(err, "failed to write corpus observation")
+		}
+	}
+
+	return errors.Wrap(bw.Flush(), "failed to flush corpus")
+}
+
+// Load reads a corpus written by Save, validating that its parameter schema
+// (names, bounds, and order) matches o's params before replaying each
+// logged observation via Log. The header's round count is restored into
+// Rounds() afterwards, so a resumed Optimizer doesn't repeat random rounds
+// that were already spent building the loaded observations.
+func (o *Optimizer) Load(r io.Reader) error {
+	o.mu.Lock()
+	params := append([]Param{}, o.mu.params...)
+	o.mu.loadingCorpus = true
+	o.mu.Unlock()
+	defer func() {
+		o.mu.Lock()
+		o.mu.loadingCorpus = false
+		o.mu.Unlock()
+	}()
+
+	scanner := bufio.NewScanner(r)
+
+	if !scanner.Scan() {
+		return errors.Wrap(scanner.Err(), "empty corpus")
+	}
+	if got := scanner.Text(); got != corpusFormat {
+		return errors.Errorf("unrecognized corpus header %q; want %q", got, corpusFormat)
+	}
+
+	if !scanner.Scan() {
+		return errors.Wrap(scanner.Err(), "corpus missing round header")
+	}
+	var round int
+	if _, err := fmt.Sscanf(scanner.Text(), "round %d", &round); err != nil {
+		return errors.Wrapf(err, "invalid corpus round header %q", scanner.Text())
+	}
+
+	for _, p := range params {
+		if !scanner.Scan() {
+			return errors.Wrap(scanner.Err(), "corpus truncated before param schema")
+		}
+		var name string
+		var min, max float64
+		line := scanner.Text()
+		if _, err := fmt.Sscanf(line, "param %q %g %g", &name, &min, &max); err != nil {
+			return errors.Wrapf(err, "invalid corpus param line %q", line)
+		}
+		if name != p.GetName() || min != p.GetMin() || max != p.GetMax() {
+			return errors.Errorf(
+				"corpus schema mismatch: got param %q [%g, %g]; want %q [%g, %g]",
+				name, min, max, p.GetName(), p.GetMin(), p.GetMax())
+		}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		before, after, ok := strings.Cut(line, " -> ")
+		if !ok {
+			return errors.Errorf("invalid corpus observation line %q", line)
+		}
+		fields := strings.Fields(before)
+		if len(fields) != len(params) {
+			return errors.Errorf("corpus observation %q has %d values; want %d", line, len(fields), len(params))
+		}
+		y, err := strconv.ParseFloat(after, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid corpus observation value in %q", line)
+		}
+		vals := make(map[Param]float64, len(params))
+		for i, p := range params {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return errors.Wrapf(err, "invalid corpus observation value in %q", line)
+			}
+			vals[p] = v
+		}
+		o.Log(vals, y)
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read corpus")
+	}
+
+	o.mu.Lock()
+	o.mu.round = round
+	var persistErr error
+	if o.mu.corpusDir != "" {
+		persistErr = o.persistCorpusLocked()
+	}
+	o.mu.Unlock()
+	if persistErr != nil {
+		return errors.Wrap(persistErr, "failed to persist corpus after load")
+	}
+
+	return nil
+}
+
+// trainGP refits the GP's hyperparameters every hyperTrainEvery observations,
+// per WithHyperTraining. Errors are swallowed: LearnHyperparameters leaves
+// the GP's hyperparameters untouched on failure, so there's nothing to
+// recover from. Callers must hold o.mu.
+func (o *Optimizer) trainGP() {
+	if o.mu.hyperTrainEvery <= 0 {
+		return
+	}
+	if n := o.mu.gp.Len(); n == 0 || n%o.mu.hyperTrainEvery != 0 {
+		return
 	}
-	o.mu.gp.Add(xa, y)
+	o.mu.gp.LearnHyperparameters(o.mu.rng, o.mu.hyperTrainNoise)
 }
 
 // Optimize will call f the fewest times as possible while trying to maximize
@@ -336,16 +927,21 @@ func (o *Optimizer) Optimize(f func(map[Param]float64) float64) (x map[Param]flo
 		if x == nil {
 			break
 		}
-		if parallel {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
 
-				o.Log(x, f(x))
-			}()
-		} else {
-			wg.Wait()
+		// Every point is evaluated in its own goroutine, including the last
+		// one of a batch, so that a full batch overlaps instead of the last
+		// candidate blocking on the rest. If parallel is false, this is the
+		// last point of the current round/batch, so wait for it (and
+		// everything launched before it) before asking for the next one,
+		// which may depend on these results being logged.
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
 			o.Log(x, f(x))
+		}()
+		if !parallel {
+			wg.Wait()
 		}
 	}
 
@@ -359,10 +955,7 @@ func (o *Optimizer) Optimize(f func(map[Param]float64) float64) (x map[Param]flo
 	} else {
 		xa, y = o.mu.gp.Maximum()
 	}
-	x = map[Param]float64{}
-	for i, v := range xa {
-		x[o.mu.params[i]] = v
-	}
+	x = o.paramMap(xa)
 
 	return x, y, nil
 }
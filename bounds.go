@@ -1,6 +1,8 @@
 package bayesopt
 
 import (
+	"math"
+
 	"github.com/pkg/errors"
 	"gonum.org/v1/gonum/optimize"
 )
@@ -8,25 +10,53 @@ import (
 var _ optimize.Method = BoundsMethod{}
 var _ optimize.Statuser = BoundsMethod{}
 
+// BoundsMethod wraps a Method so that every point it proposes stays within
+// Bounds. Each trial point is rescaled along the line from the last accepted
+// major iteration rather than clamped coordinate-by-coordinate: a
+// per-coordinate clamp can move a trial point off the line search's
+// direction entirely, which breaks a Linesearcher's assumption that it's
+// evaluating phi(step) = f(x + step*dir) and leads to spurious
+// ErrLinesearcherFailure/ErrNoProgress errors that end the local search
+// early.
 type BoundsMethod struct {
 	Method optimize.Method
 	Bounds []Param
 }
 
-func (m BoundsMethod) constrain(loc *optimize.Location) {
-	if loc == nil {
-		return
+// boundTrial rescales x toward last so it lies within min/max, shortening
+// the step along x-last rather than moving it off that line. With no prior
+// accepted point to scale from, it falls back to a per-coordinate clamp.
+func boundTrial(x, last, min, max []float64) []float64 {
+	if last == nil {
+		out := append([]float64{}, x...)
+		for i := range out {
+			if out[i] > max[i] {
+				out[i] = max[i]
+			} else if out[i] < min[i] {
+				out[i] = min[i]
+			}
+		}
+		return out
 	}
 
-	for i, param := range m.Bounds {
-		max := param.GetMax()
-		min := param.GetMin()
-		if loc.X[i] > max {
-			loc.X[i] = max
-		} else if loc.X[i] < min {
-			loc.X[i] = min
+	t := 1.0
+	for i := range x {
+		step := x[i] - last[i]
+		if step > 0 && x[i] > max[i] {
+			t = math.Min(t, (max[i]-last[i])/step)
+		} else if step < 0 && x[i] < min[i] {
+			t = math.Min(t, (min[i]-last[i])/step)
 		}
 	}
+	if t < 0 {
+		t = 0
+	}
+
+	out := make([]float64, len(x))
+	for i := range x {
+		out[i] = last[i] + t*(x[i]-last[i])
+	}
+	return out
 }
 
 func (m BoundsMethod) Init(dims, tasks int) int {
@@ -37,11 +67,17 @@ func (m BoundsMethod) Run(operation chan<- optimize.Task, result <-chan optimize
 	op := make(chan optimize.Task)
 	res := make(chan optimize.Task)
 
+	min, max := paramBounds(m.Bounds)
+	// last is the most recently accepted MajorIteration point; trial points
+	// are bounded relative to it so the effective step only ever shrinks,
+	// never changes direction. It's only touched from this goroutine and the
+	// op-forwarding goroutine below, which run strictly one after another.
+	var last []float64
+
 	go func() {
 		defer close(res)
 
 		for t := range result {
-			m.constrain(t.Location)
 			res <- t
 		}
 	}()
@@ -50,13 +86,22 @@ func (m BoundsMethod) Run(operation chan<- optimize.Task, result <-chan optimize
 		defer close(operation)
 
 		for t := range op {
-			m.constrain(t.Location)
+			if t.Location != nil {
+				if t.Op == optimize.MajorIteration {
+					last = append([]float64{}, t.Location.X...)
+				} else {
+					t.Location.X = boundTrial(t.Location.X, last, min, max)
+				}
+			}
 			operation <- t
 		}
 	}()
 
 	for _, t := range tasks {
-		m.constrain(t.Location)
+		if t.Location != nil {
+			t.Location.X = boundTrial(t.Location.X, last, min, max)
+			last = append([]float64{}, t.Location.X...)
+		}
 	}
 	m.Method.Run(op, res, tasks)
 }
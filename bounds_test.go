@@ -0,0 +1,53 @@
+package bayesopt
+
+import "testing"
+
+func TestBoundTrialNoLast(t *testing.T) {
+	t.Parallel()
+
+	min := []float64{0, 0}
+	max := []float64{10, 10}
+
+	got := boundTrial([]float64{-1, 15}, nil, min, max)
+	want := []float64{0, 10}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("boundTrial()[%d] = %f; want %f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBoundTrialScalesAlongDirection(t *testing.T) {
+	t.Parallel()
+
+	min := []float64{0, 0}
+	max := []float64{10, 10}
+	last := []float64{5, 5}
+
+	// Stepping to (15, 7) overshoots the x bound; the whole step should
+	// shrink along (x-last) rather than just clamping x, so y also moves
+	// less than requested.
+	got := boundTrial([]float64{15, 7}, last, min, max)
+	if got[0] != max[0] {
+		t.Errorf("boundTrial()[0] = %f; want %f", got[0], max[0])
+	}
+	if got[1] <= last[1] || got[1] >= 7 {
+		t.Errorf("boundTrial()[1] = %f; want strictly between %f and 7", got[1], last[1])
+	}
+}
+
+func TestBoundTrialWithinBounds(t *testing.T) {
+	t.Parallel()
+
+	min := []float64{0, 0}
+	max := []float64{10, 10}
+	last := []float64{5, 5}
+
+	x := []float64{6, 4}
+	got := boundTrial(x, last, min, max)
+	for i := range x {
+		if got[i] != x[i] {
+			t.Errorf("boundTrial()[%d] = %f; want unchanged %f", i, got[i], x[i])
+		}
+	}
+}
@@ -3,6 +3,8 @@ package bayesopt
 import (
 	"math"
 
+	"gonum.org/v1/gonum/stat/distuv"
+
 	"github.com/d4l3k/go-bayesopt/gp"
 )
 
@@ -11,6 +13,18 @@ type Exploration interface {
 	Estimate(gp *gp.GP, minimize bool, x []float64) (float64, error)
 }
 
+// GradientExploration is implemented by Explorations that can supply an
+// analytic gradient of their Estimate. When o.mu.exploration implements it,
+// selectCandidate uses Gradient directly instead of falling back to the
+// gradient of the GP's posterior mean, which is only an approximation for
+// acquisition functions, such as ExpectedImprovement and
+// ProbabilityOfImprovement, that also depend on the posterior uncertainty.
+type GradientExploration interface {
+	Exploration
+
+	Gradient(gp *gp.GP, minimize bool, x []float64) ([]float64, error)
+}
+
 // UCB implements upper confidence bound exploration.
 type UCB struct {
 	Kappa float64
@@ -28,6 +42,190 @@ func (e UCB) Estimate(gp *gp.GP, minimize bool, x []float64) (float64, error) {
 	return mean + e.Kappa*sd, nil
 }
 
+// Gradient implements GradientExploration.
+func (e UCB) Gradient(g *gp.GP, minimize bool, x []float64) ([]float64, error) {
+	meanGrad, sdGrad, err := sdGradient(g, x)
+	if err != nil {
+		return nil, err
+	}
+	grad := make([]float64, len(x))
+	sign := 1.0
+	if minimize {
+		sign = -1
+	}
+	for i := range grad {
+		grad[i] = meanGrad[i] + sign*e.Kappa*sdGrad[i]
+	}
+	return grad, nil
+}
+
+// standardNormal is the shared N(0, 1) distribution used to compute the CDF
+// (Phi) and PDF (phi) in the improvement based acquisition functions below.
+var standardNormal = distuv.Normal{Mu: 0, Sigma: 1}
+
+// sdGradient returns the gradient of the GP's posterior mean and standard
+// deviation at x, the building blocks each GradientExploration below
+// differentiates through. The standard deviation gradient is derived from
+// gp.GP.VarianceGradient via d(sqrt(v))/dx = v'(x) / (2*sqrt(v)); at sd == 0
+// it's reported as all zero, matching the sd == 0 special case in Estimate.
+func sdGradient(g *gp.GP, x []float64) (meanGrad, sdGrad []float64, err error) {
+	meanGrad, err = g.Gradient(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	varGrad, err := g.VarianceGradient(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, sd, err := g.Estimate(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	sdGrad = make([]float64, len(x))
+	if sd > 0 {
+		for i, v := range varGrad {
+			sdGrad[i] = v / (2 * sd)
+		}
+	}
+	return meanGrad, sdGrad, nil
+}
+
+// best returns the incumbent best observed value, accounting for whether
+// we're minimizing or maximizing.
+func best(gp *gp.GP, minimize bool) float64 {
+	if minimize {
+		_, y := gp.Minimum()
+		return y
+	}
+	_, y := gp.Maximum()
+	return y
+}
+
+// ExpectedImprovement implements the expected improvement (EI) acquisition
+// function. It favors points that are expected to improve on the best
+// observed value by the largest margin, trading off the posterior mean
+// against the posterior uncertainty.
+type ExpectedImprovement struct {
+	// Xi is the exploration-exploitation tradeoff. Larger values encourage
+	// more exploration. Defaults to 0 if unset.
+	Xi float64
+}
+
+// Estimate implements Exploration.
+func (e ExpectedImprovement) Estimate(gp *gp.GP, minimize bool, x []float64) (float64, error) {
+	mean, sd, err := gp.Estimate(x)
+	if err != nil {
+		return 0, err
+	}
+	if sd == 0 {
+		return 0, nil
+	}
+	fBest := best(gp, minimize)
+	var improve float64
+	if minimize {
+		improve = fBest - mean - e.Xi
+	} else {
+		improve = mean - fBest - e.Xi
+	}
+	z := improve / sd
+	return improve*standardNormal.CDF(z) + sd*standardNormal.Prob(z), nil
+}
+
+// Gradient implements GradientExploration. Differentiating Estimate's
+// improve*Phi(z) + sd*phi(z) with respect to improve and sd both simplify
+// to Phi(z) and phi(z) respectively (the phi'(z) = -z*phi(z) terms cancel),
+// leaving dEI/dx = Phi(z)*d(improve)/dx + phi(z)*d(sd)/dx.
+func (e ExpectedImprovement) Gradient(g *gp.GP, minimize bool, x []float64) ([]float64, error) {
+	mean, sd, err := g.Estimate(x)
+	if err != nil {
+		return nil, err
+	}
+	grad := make([]float64, len(x))
+	if sd == 0 {
+		return grad, nil
+	}
+	meanGrad, sdGrad, err := sdGradient(g, x)
+	if err != nil {
+		return nil, err
+	}
+	fBest := best(g, minimize)
+	var improve, sign float64
+	if minimize {
+		improve = fBest - mean - e.Xi
+		sign = -1
+	} else {
+		improve = mean - fBest - e.Xi
+		sign = 1
+	}
+	z := improve / sd
+	cdf := standardNormal.CDF(z)
+	pdf := standardNormal.Prob(z)
+	for i := range grad {
+		grad[i] = cdf*sign*meanGrad[i] + pdf*sdGrad[i]
+	}
+	return grad, nil
+}
+
+// ProbabilityOfImprovement implements the probability of improvement (PI)
+// acquisition function. It estimates the probability that a point improves
+// on the best observed value by at least Xi.
+type ProbabilityOfImprovement struct {
+	// Xi is the exploration-exploitation tradeoff. Larger values encourage
+	// more exploration. Defaults to 0 if unset.
+	Xi float64
+}
+
+// Estimate implements Exploration.
+func (e ProbabilityOfImprovement) Estimate(gp *gp.GP, minimize bool, x []float64) (float64, error) {
+	mean, sd, err := gp.Estimate(x)
+	if err != nil {
+		return 0, err
+	}
+	if sd == 0 {
+		return 0, nil
+	}
+	fBest := best(gp, minimize)
+	var improve float64
+	if minimize {
+		improve = fBest - mean - e.Xi
+	} else {
+		improve = mean - fBest - e.Xi
+	}
+	return standardNormal.CDF(improve / sd), nil
+}
+
+// Gradient implements GradientExploration. PI = Phi(z), so by the chain
+// rule dPI/dx = phi(z)/sd * d(improve)/dx - phi(z)*z/sd * d(sd)/dx.
+func (e ProbabilityOfImprovement) Gradient(g *gp.GP, minimize bool, x []float64) ([]float64, error) {
+	mean, sd, err := g.Estimate(x)
+	if err != nil {
+		return nil, err
+	}
+	grad := make([]float64, len(x))
+	if sd == 0 {
+		return grad, nil
+	}
+	meanGrad, sdGrad, err := sdGradient(g, x)
+	if err != nil {
+		return nil, err
+	}
+	fBest := best(g, minimize)
+	var improve, sign float64
+	if minimize {
+		improve = fBest - mean - e.Xi
+		sign = -1
+	} else {
+		improve = mean - fBest - e.Xi
+		sign = 1
+	}
+	z := improve / sd
+	pdf := standardNormal.Prob(z)
+	for i := range grad {
+		grad[i] = pdf/sd*sign*meanGrad[i] - pdf*z/sd*sdGrad[i]
+	}
+	return grad, nil
+}
+
 // BarrierFunc returns a value that is added to the value to bound the
 // optimization.
 type BarrierFunc func(x []float64, params []Param) float64
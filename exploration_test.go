@@ -0,0 +1,143 @@
+package bayesopt
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/floats/scalar"
+
+	"github.com/d4l3k/go-bayesopt/gp"
+)
+
+// quadraticGP returns a GP fit to a handful of samples of y = x^2, mimicking
+// the quadratic test problem used in bayesopt_test.go.
+func quadraticGP() *gp.GP {
+	g := gp.New(gp.MaternCov{}, 0)
+	for _, x := range []float64{-10, -5, -1, 0, 1, 5, 10} {
+		g.Add([]float64{x}, math.Pow(x, 2))
+	}
+	return g
+}
+
+func TestExpectedImprovement(t *testing.T) {
+	t.Parallel()
+
+	g := quadraticGP()
+	e := ExpectedImprovement{Xi: 0}
+
+	// Exactly at the known minimum there's no uncertainty, so there's no
+	// room for improvement.
+	atBest, err := e.Estimate(g, true, []float64{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atBest != 0 {
+		t.Errorf("ExpectedImprovement(atBest) = %f; want 0", atBest)
+	}
+
+	// A nearby unsampled point has a mean close to the best observed value
+	// and positive uncertainty, so it should have positive expected
+	// improvement.
+	near, err := e.Estimate(g, true, []float64{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if near <= 0 {
+		t.Errorf("ExpectedImprovement(near) = %f; want > 0", near)
+	}
+
+	// Far from any observation the predicted mean is confidently much worse
+	// than the best observed value, so expected improvement should be
+	// negligible in comparison.
+	far, err := e.Estimate(g, true, []float64{-7.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if far >= near {
+		t.Errorf("ExpectedImprovement(far) = %f; want < ExpectedImprovement(near) = %f", far, near)
+	}
+}
+
+func TestProbabilityOfImprovement(t *testing.T) {
+	t.Parallel()
+
+	g := quadraticGP()
+	p := ProbabilityOfImprovement{Xi: 0}
+
+	atBest, err := p.Estimate(g, true, []float64{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atBest != 0 {
+		t.Errorf("ProbabilityOfImprovement(atBest) = %f; want 0", atBest)
+	}
+
+	near, err := p.Estimate(g, true, []float64{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	far, err := p.Estimate(g, true, []float64{-7.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []float64{atBest, near, far} {
+		if v < 0 || v > 1 {
+			t.Errorf("ProbabilityOfImprovement = %f; want in [0, 1]", v)
+		}
+	}
+	if far >= near {
+		t.Errorf("ProbabilityOfImprovement(far) = %f; want < ProbabilityOfImprovement(near) = %f", far, near)
+	}
+}
+
+// numExplorationGrad approximates the gradient of e.Estimate at x using
+// central differences, for comparison against each GradientExploration's
+// analytic Gradient.
+func numExplorationGrad(e Exploration, g *gp.GP, minimize bool, x []float64) []float64 {
+	const h = 1e-5
+	grad := make([]float64, len(x))
+	xh := append([]float64{}, x...)
+	estimate := func(x []float64) float64 {
+		v, err := e.Estimate(g, minimize, x)
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}
+	for i := range x {
+		orig := xh[i]
+		xh[i] = orig + h
+		plus := estimate(xh)
+		xh[i] = orig - h
+		minus := estimate(xh)
+		xh[i] = orig
+		grad[i] = (plus - minus) / (2 * h)
+	}
+	return grad
+}
+
+func TestGradientExplorations(t *testing.T) {
+	t.Parallel()
+
+	g := quadraticGP()
+	x := []float64{0.5}
+
+	for _, minimize := range []bool{true, false} {
+		for name, e := range map[string]GradientExploration{
+			"UCB": UCB{Kappa: 1.96},
+			"EI":  ExpectedImprovement{Xi: 0},
+			"PI":  ProbabilityOfImprovement{Xi: 0},
+		} {
+			got, err := e.Gradient(g, minimize, x)
+			if err != nil {
+				t.Fatalf("%s(minimize=%v): %+v", name, minimize, err)
+			}
+			want := numExplorationGrad(e, g, minimize, x)
+			for i := range want {
+				if !scalar.EqualWithinAbs(got[i], want[i], 1e-3) {
+					t.Errorf("%s(minimize=%v).Gradient()[%d] = %f; want %f", name, minimize, i, got[i], want[i])
+				}
+			}
+		}
+	}
+}
@@ -33,3 +33,38 @@ func TestMaternCov(t *testing.T) {
 		}
 	}
 }
+
+// numCovGrad approximates the gradient of cov.Cov(a, b) with respect to a
+// using central differences, for comparison against a Cov's analytic Grad.
+func numCovGrad(cov Cov, a, b []float64) []float64 {
+	const h = 1e-5
+	grad := make([]float64, len(a))
+	ah := append([]float64{}, a...)
+	for i := range a {
+		orig := ah[i]
+		ah[i] = orig + h
+		plus := cov.Cov(ah, b)
+		ah[i] = orig - h
+		minus := cov.Cov(ah, b)
+		ah[i] = orig
+		grad[i] = (plus - minus) / (2 * h)
+	}
+	return grad
+}
+
+func TestMaternARDGrad(t *testing.T) {
+	cov := MaternARD{
+		Lengthscales: []float64{1, 2},
+		SignalVar:    1.5,
+	}
+	a := []float64{0.3, 1.1}
+	b := []float64{-0.2, 0.4}
+
+	got := cov.Grad(a, b)
+	want := numCovGrad(cov, a, b)
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 0.001 {
+			t.Errorf("MaternARD{}.Grad(%+v, %+v)[%d] = %f; want %f", a, b, i, got[i], want[i])
+		}
+	}
+}
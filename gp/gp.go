@@ -15,6 +15,13 @@ import (
 
 var ErrFactorizeFailed = errors.New("failed to factorize")
 
+// jitter is a small diagonal nugget added to the covariance matrix on top of
+// the configured noise, so that duplicate or near-duplicate training points
+// (which a noiseless GP, noise == 0, can otherwise pick up from tightly
+// converging acquisition optimization) don't make the Cholesky factorization
+// singular.
+const jitter = 1e-8
+
 // GP represents a gaussian process.
 type GP struct {
 	inputs  [][]float64
@@ -84,6 +91,21 @@ func (gp GP) Dims() int {
 	return 0
 }
 
+// Len returns the number of observations added so far.
+func (gp GP) Len() int {
+	return len(gp.outputs)
+}
+
+// Cov returns the covariance function the GP is using.
+func (gp GP) Cov() Cov {
+	return gp.cov
+}
+
+// Noise returns the observation noise variance the GP is using.
+func (gp GP) Noise() float64 {
+	return gp.noise
+}
+
 // Add bulk adds XY pairs.
 func (gp *GP) Add(x []float64, y float64) {
 	gp.dirty = true
@@ -91,6 +113,16 @@ func (gp *GP) Add(x []float64, y float64) {
 	gp.outputs = append(gp.outputs, y)
 }
 
+// Truncate discards all observations after the first n, returning the GP to
+// the state it was in after n calls to Add. It's used to roll back
+// temporary observations, such as the fake ones added during batch
+// acquisition.
+func (gp *GP) Truncate(n int) {
+	gp.dirty = true
+	gp.inputs = gp.inputs[:n]
+	gp.outputs = gp.outputs[:n]
+}
+
 func isConditionErr(err error) bool {
 	_, ok := err.(mat.Condition)
 	return ok
@@ -114,7 +146,19 @@ func (gp *GP) compute() error {
 	}
 	var L mat.Cholesky
 	if ok := L.Factorize(k); !ok {
-		return errors.Wrap(ErrFactorizeFailed, "compute")
+		// Duplicate or near-duplicate training points (which tightly
+		// converging acquisition optimization can produce against a
+		// noiseless GP) make the matrix singular; retry with a small
+		// diagonal nugget rather than giving up. Estimate's kstar and
+		// Cov(x, x) terms are deliberately left un-jittered, so the
+		// posterior variance it computes at a distinct, non-duplicated
+		// training point stays between 0 and jitter.
+		for i := 0; i < n; i++ {
+			k.SetSym(i, i, k.At(i, i)+jitter)
+		}
+		if ok := L.Factorize(k); !ok {
+			return errors.Wrap(ErrFactorizeFailed, "compute")
+		}
 	}
 	b := mat.NewVecDense(n, gp.normOutputs())
 	alpha := mat.NewVecDense(n, nil)
@@ -130,6 +174,12 @@ func (gp *GP) compute() error {
 
 func (gp *GP) normOutputs() []float64 {
 	gp.mean, gp.stddev = stat.MeanStdDev(gp.outputs, nil)
+	if gp.stddev == 0 {
+		// All logged outputs are identical (e.g. an Optimizer stuck
+		// repeatedly proposing the same bound-clamped candidate); leave the
+		// outputs unnormalized instead of dividing by zero into NaN.
+		gp.stddev = 1
+	}
 	out := make([]float64, len(gp.outputs))
 	for i, v := range gp.outputs {
 		out[i] = (v - gp.mean) / gp.stddev
@@ -157,6 +207,13 @@ func (gp *GP) Estimate(x []float64) (float64, float64, error) {
 		return 0, 0, errors.Wrap(err, "failed to find v")
 	}
 	variance := gp.cov.Cov(x, x) - mat.Dot(kstar, v)
+	if variance < 0 {
+		// Floating-point round-off in the matrix solve can push the true
+		// posterior variance (~0 right at a training point) infinitesimally
+		// negative; clamp rather than feeding Sqrt a negative number and
+		// getting NaN back out.
+		variance = 0
+	}
 	sd := math.Sqrt(variance)
 
 	return mean, sd, nil
@@ -173,7 +230,9 @@ func (gp *GP) Gradient(x []float64) ([]float64, error) {
 
 	kstar := mat.NewDense(len(x), n, nil)
 	for i := 0; i < n; i++ {
-		kstar.SetCol(i, gp.cov.Grad(gp.inputs[i], x))
+		// Grad is documented with respect to its first argument, so pass x
+		// first to get d/dx Cov(x, gp.inputs[i]) rather than d/d(input).
+		kstar.SetCol(i, gp.cov.Grad(x, gp.inputs[i]))
 	}
 
 	grad := mat.NewVecDense(len(x), nil)
@@ -183,6 +242,43 @@ func (gp *GP) Gradient(x []float64) ([]float64, error) {
 	return grad.RawVector().Data, nil
 }
 
+// VarianceGradient returns the gradient of the posterior variance (the
+// squared standard deviation returned by Estimate) at the point x. It's
+// used alongside Gradient by acquisition functions, such as
+// ExpectedImprovement and ProbabilityOfImprovement, whose value depends on
+// both the posterior mean and its uncertainty.
+func (gp *GP) VarianceGradient(x []float64) ([]float64, error) {
+	if gp.dirty {
+		if err := gp.compute(); err != nil {
+			return nil, errors.Wrap(err, "failed to run compute")
+		}
+	}
+	n := gp.n
+
+	kstar := mat.NewVecDense(n, nil)
+	dkstar := mat.NewDense(len(x), n, nil)
+	for i := 0; i < n; i++ {
+		kstar.SetVec(i, gp.cov.Cov(gp.inputs[i], x))
+		// Grad is documented with respect to its first argument, so pass x
+		// first to get d/dx Cov(x, gp.inputs[i]) rather than d/d(input).
+		dkstar.SetCol(i, gp.cov.Grad(x, gp.inputs[i]))
+	}
+
+	v := mat.NewVecDense(n, nil)
+	if err := gp.l.SolveVecTo(v, kstar); err != nil && !isConditionErr(err) {
+		return nil, errors.Wrap(err, "failed to find v")
+	}
+
+	// variance = Cov(x, x) - kstar^T K^-1 kstar; Cov(x, x) is constant for
+	// the stationary kernels this package implements, so only the second
+	// term contributes a gradient.
+	grad := mat.NewVecDense(len(x), nil)
+	grad.MulVec(dkstar, v)
+	grad.ScaleVec(-2, grad)
+
+	return grad.RawVector().Data, nil
+}
+
 // Minimum returns the minimum value logged.
 func (gp *GP) Minimum() (x []float64, y float64) {
 	i := floats.MinIdx(gp.outputs)
@@ -5,7 +5,7 @@ import (
 	"math/rand"
 	"testing"
 
-	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/floats/scalar"
 
 	"github.com/d4l3k/go-bayesopt/gp"
 	"github.com/d4l3k/go-bayesopt/gp/plot"
@@ -20,7 +20,7 @@ func gpAdd(gp *gp.GP, x, y float64) {
 }
 
 func TestKnown(t *testing.T) {
-	gp := gp.New(gp.MaternCov, 0)
+	gp := gp.New(gp.MaternCov{}, 0)
 
 	gpAdd(gp, 0.25, 0.75)
 
@@ -35,10 +35,56 @@ func TestKnown(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !floats.EqualWithinAbs(mean, f(0.25, 0.75), 0.0001) {
+	if !scalar.EqualWithinAbs(mean, f(0.25, 0.75), 0.0001) {
 		t.Fatalf("got mean = %f; not 1", mean)
 	}
-	if !floats.EqualWithinAbs(variance, 0, 0.0001) {
+	if !scalar.EqualWithinAbs(variance, 0, 0.0001) {
 		t.Fatalf("got variance = %f; not 0", variance)
 	}
 }
+
+// numVarGrad approximates the gradient of g.Estimate's variance (the
+// squared second return value) at x using central differences.
+func numVarGrad(g *gp.GP, x []float64) []float64 {
+	const h = 1e-5
+	grad := make([]float64, len(x))
+	xh := append([]float64{}, x...)
+	variance := func(x []float64) float64 {
+		_, sd, err := g.Estimate(x)
+		if err != nil {
+			panic(err)
+		}
+		return sd * sd
+	}
+	for i := range x {
+		orig := xh[i]
+		xh[i] = orig + h
+		plus := variance(xh)
+		xh[i] = orig - h
+		minus := variance(xh)
+		xh[i] = orig
+		grad[i] = (plus - minus) / (2 * h)
+	}
+	return grad
+}
+
+func TestVarianceGradient(t *testing.T) {
+	t.Parallel()
+
+	g := gp.New(gp.MaternCov{}, 0)
+	gpAdd(g, 0.25, 0.75)
+	gpAdd(g, -1, 2)
+	gpAdd(g, 3, -2)
+
+	x := []float64{0.6, 1.1}
+	got, err := g.VarianceGradient(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := numVarGrad(g, x)
+	for i := range want {
+		if !scalar.EqualWithinAbs(got[i], want[i], 1e-3) {
+			t.Errorf("VarianceGradient()[%d] = %f; want %f", i, got[i], want[i])
+		}
+	}
+}
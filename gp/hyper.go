@@ -0,0 +1,309 @@
+package gp
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/optimize"
+)
+
+// DefaultHyperRestarts is the number of random restarts LearnHyperparameters
+// tries when maximizing the marginal likelihood, to guard against local
+// optima.
+var DefaultHyperRestarts = 5
+
+// numGradStep is the central difference step size used to approximate the
+// gradient of the marginal likelihood with respect to the log
+// hyperparameters.
+const numGradStep = 1e-5
+
+// randNormFloat64, randExpFloat64, and randFloat64 draw from rng if it's
+// non-nil, falling back to the math/rand top-level source otherwise, so
+// callers that don't have a seeded *rand.Rand handy (e.g.
+// IntegratedAcquisition) keep working unchanged.
+func randNormFloat64(rng *rand.Rand) float64 {
+	if rng != nil {
+		return rng.NormFloat64()
+	}
+	return rand.NormFloat64()
+}
+
+func randExpFloat64(rng *rand.Rand) float64 {
+	if rng != nil {
+		return rng.ExpFloat64()
+	}
+	return rand.ExpFloat64()
+}
+
+func randFloat64(rng *rand.Rand) float64 {
+	if rng != nil {
+		return rng.Float64()
+	}
+	return rand.Float64()
+}
+
+// LearnHyperparameters fits the GP's covariance hyperparameters (length
+// scales and signal variance) to the observed data by maximizing the log
+// marginal likelihood, restarting from several random points to avoid local
+// optima. The GP's covariance function must implement ARDCov. If trainNoise
+// is true, the observation noise variance is fit alongside the covariance
+// hyperparameters instead of being held fixed. rng draws the restarts'
+// jitter; pass nil to fall back to the math/rand top-level source.
+func (gp *GP) LearnHyperparameters(rng *rand.Rand, trainNoise bool) error {
+	ard, ok := gp.cov.(ARDCov)
+	if !ok {
+		return errors.Errorf("LearnHyperparameters: cov %T does not implement ARDCov", gp.cov)
+	}
+	if len(gp.inputs) == 0 {
+		return errors.New("LearnHyperparameters: no observations to fit")
+	}
+
+	hyper := ard.Hyperparameters()
+	logInit := make([]float64, len(hyper))
+	for i, v := range hyper {
+		logInit[i] = math.Log(v)
+	}
+	if trainNoise {
+		logInit = append(logInit, math.Log(gp.noise))
+	}
+
+	negLogLikelihood := func(logTheta []float64) float64 {
+		theta := make([]float64, len(hyper))
+		for i, v := range logTheta[:len(hyper)] {
+			theta[i] = math.Exp(v)
+		}
+		noise := gp.noise
+		if trainNoise {
+			noise = math.Exp(logTheta[len(hyper)])
+		}
+		nll, err := gp.negLogMarginalLikelihood(ard.WithHyperparameters(theta), noise)
+		if err != nil {
+			// Steer the optimizer away from ill-conditioned kernels instead
+			// of failing outright.
+			return math.Inf(1)
+		}
+		return nll
+	}
+	problem := optimize.Problem{
+		Func: negLogLikelihood,
+		Grad: func(grad, x []float64) {
+			copy(grad, numGrad(negLogLikelihood, x))
+		},
+	}
+
+	starts := make([][]float64, DefaultHyperRestarts)
+	starts[0] = logInit
+	for i := 1; i < len(starts); i++ {
+		start := make([]float64, len(logInit))
+		for j, v := range logInit {
+			start[j] = v + randNormFloat64(rng)
+		}
+		starts[i] = start
+	}
+
+	bestNLL := math.Inf(1)
+	var bestTheta []float64
+	for _, start := range starts {
+		result, err := optimize.Minimize(problem, start, nil, &optimize.LBFGS{})
+		// Linesearch/no-progress failures still leave result.X at the best
+		// point found so far, so they're worth keeping; only a missing
+		// result means the restart found nothing usable.
+		if result == nil || (err != nil && !isNonFatalOptimizeErr(err)) {
+			continue
+		}
+		if result.F < bestNLL {
+			bestNLL = result.F
+			bestTheta = result.X
+		}
+	}
+	if bestTheta == nil {
+		return errors.New("LearnHyperparameters: all restarts failed to converge")
+	}
+
+	theta := make([]float64, len(hyper))
+	for i, v := range bestTheta[:len(hyper)] {
+		theta[i] = math.Exp(v)
+	}
+	gp.cov = ard.WithHyperparameters(theta)
+	if trainNoise {
+		gp.noise = math.Exp(bestTheta[len(hyper)])
+	}
+	gp.dirty = true
+	return nil
+}
+
+// DefaultSliceStepSize is the initial step-out width SampleHyperparameters
+// uses to bracket each log-hyperparameter's conditional distribution.
+var DefaultSliceStepSize = 1.0
+
+// SampleHyperparameters draws n samples from the (approximate) posterior
+// over the GP's covariance hyperparameters via component-wise slice
+// sampling in log-space. This lets an acquisition function marginalize over
+// hyperparameter uncertainty instead of conditioning on a single point
+// estimate, which can be misleading with few observations. The GP's
+// covariance function must implement ARDCov. The chain starts from the GP's
+// current hyperparameters, so call LearnHyperparameters first to center it
+// near the mode. rng drives the slice sampling; pass nil to fall back to the
+// math/rand top-level source.
+func (gp *GP) SampleHyperparameters(rng *rand.Rand, n int) ([][]float64, error) {
+	ard, ok := gp.cov.(ARDCov)
+	if !ok {
+		return nil, errors.Errorf("SampleHyperparameters: cov %T does not implement ARDCov", gp.cov)
+	}
+	if len(gp.inputs) == 0 {
+		return nil, errors.New("SampleHyperparameters: no observations to sample over")
+	}
+
+	logTheta := make([]float64, len(ard.Hyperparameters()))
+	for i, v := range ard.Hyperparameters() {
+		logTheta[i] = math.Log(v)
+	}
+
+	logDensity := func(logTheta []float64) (float64, error) {
+		theta := make([]float64, len(logTheta))
+		for i, v := range logTheta {
+			theta[i] = math.Exp(v)
+		}
+		nll, err := gp.negLogMarginalLikelihood(ard.WithHyperparameters(theta), gp.noise)
+		if err != nil {
+			return 0, err
+		}
+		return -nll, nil
+	}
+
+	cur, err := logDensity(logTheta)
+	if err != nil {
+		return nil, errors.Wrap(err, "SampleHyperparameters: evaluating initial point")
+	}
+
+	samples := make([][]float64, n)
+	for s := 0; s < n; s++ {
+		for i := range logTheta {
+			next, density, err := sliceSample1D(rng, logTheta, i, cur, DefaultSliceStepSize, logDensity)
+			if err != nil {
+				return nil, errors.Wrap(err, "SampleHyperparameters")
+			}
+			logTheta = next
+			cur = density
+		}
+		theta := make([]float64, len(logTheta))
+		for i, v := range logTheta {
+			theta[i] = math.Exp(v)
+		}
+		samples[s] = theta
+	}
+	return samples, nil
+}
+
+// sliceSample1D performs one step of Neal's slice sampling along dimension i
+// of x: it brackets an interval around x[i] that contains the current
+// density level by stepping out in increments of step, then repeatedly
+// shrinks the interval until a point with density above the level is found.
+// rng draws the level and bracket/shrink offsets; pass nil to fall back to
+// the math/rand top-level source.
+func sliceSample1D(rng *rand.Rand, x []float64, i int, curLogDensity, step float64, logDensity func([]float64) (float64, error)) ([]float64, float64, error) {
+	level := curLogDensity - randExpFloat64(rng)
+
+	l := append([]float64{}, x...)
+	r := append([]float64{}, x...)
+	l[i] -= step * randFloat64(rng)
+	r[i] = l[i] + step
+
+	for {
+		d, err := logDensity(l)
+		if err != nil {
+			return nil, 0, err
+		}
+		if d <= level {
+			break
+		}
+		l[i] -= step
+	}
+	for {
+		d, err := logDensity(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		if d <= level {
+			break
+		}
+		r[i] += step
+	}
+
+	for {
+		cand := append([]float64{}, x...)
+		cand[i] = l[i] + randFloat64(rng)*(r[i]-l[i])
+		d, err := logDensity(cand)
+		if err != nil {
+			return nil, 0, err
+		}
+		if d > level {
+			return cand, d, nil
+		}
+		if cand[i] < x[i] {
+			l[i] = cand[i]
+		} else {
+			r[i] = cand[i]
+		}
+	}
+}
+
+// isNonFatalOptimizeErr reports whether err is one of the expected
+// imprecision errors gonum/optimize returns when it can't fully converge but
+// still made progress, mirroring isFatalErr in the parent bayesopt package.
+func isNonFatalOptimizeErr(err error) bool {
+	switch errors.Cause(err) {
+	case optimize.ErrLinesearcherFailure, optimize.ErrNoProgress:
+		return true
+	default:
+		return false
+	}
+}
+
+// negLogMarginalLikelihood computes the negative log marginal likelihood of
+// the current observations under cov and noise, reusing the same
+// Cholesky-based machinery as compute():
+// 0.5*y^T*K^-1*y + 0.5*log|K| + n/2*log(2*pi).
+func (gp *GP) negLogMarginalLikelihood(cov Cov, noise float64) (float64, error) {
+	n := len(gp.inputs)
+	k := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			v := cov.Cov(gp.inputs[i], gp.inputs[j])
+			if i == j {
+				v += noise
+			}
+			k.SetSym(i, j, v)
+		}
+	}
+	var L mat.Cholesky
+	if ok := L.Factorize(k); !ok {
+		return 0, errors.Wrap(ErrFactorizeFailed, "negLogMarginalLikelihood")
+	}
+	y := mat.NewVecDense(n, gp.normOutputs())
+	alpha := mat.NewVecDense(n, nil)
+	if err := L.SolveVecTo(alpha, y); err != nil && !isConditionErr(err) {
+		return 0, errors.Wrap(err, "failed to solve for alpha")
+	}
+
+	nll := 0.5*mat.Dot(y, alpha) + 0.5*L.LogDet() + float64(n)/2*math.Log(2*math.Pi)
+	return nll, nil
+}
+
+// numGrad approximates the gradient of f at x using central differences.
+func numGrad(f func([]float64) float64, x []float64) []float64 {
+	grad := make([]float64, len(x))
+	xh := append([]float64{}, x...)
+	for i := range x {
+		orig := xh[i]
+		xh[i] = orig + numGradStep
+		fPlus := f(xh)
+		xh[i] = orig - numGradStep
+		fMinus := f(xh)
+		xh[i] = orig
+		grad[i] = (fPlus - fMinus) / (2 * numGradStep)
+	}
+	return grad
+}
@@ -0,0 +1,115 @@
+package gp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestLearnHyperparameters(t *testing.T) {
+	t.Parallel()
+
+	g := New(MaternARD{
+		Lengthscales: []float64{1},
+		SignalVar:    1,
+	}, 1e-4)
+	for _, x := range []float64{-10, -5, -1, 0, 1, 5, 10} {
+		g.Add([]float64{x}, math.Pow(x, 2))
+	}
+
+	if err := g.LearnHyperparameters(rand.New(rand.NewSource(1)), false); err != nil {
+		t.Fatal(err)
+	}
+
+	ard, ok := g.cov.(ARDCov)
+	if !ok {
+		t.Fatalf("cov is %T; want ARDCov", g.cov)
+	}
+	for _, v := range ard.Hyperparameters() {
+		if v <= 0 {
+			t.Errorf("hyperparameter %f; want > 0", v)
+		}
+	}
+
+	if _, _, err := g.Estimate([]float64{0}); err != nil {
+		t.Fatalf("Estimate after LearnHyperparameters: %+v", err)
+	}
+}
+
+func TestSampleHyperparameters(t *testing.T) {
+	t.Parallel()
+
+	g := New(MaternARD{
+		Lengthscales: []float64{1},
+		SignalVar:    1,
+	}, 1e-4)
+	for _, x := range []float64{-10, -5, -1, 0, 1, 5, 10} {
+		g.Add([]float64{x}, math.Pow(x, 2))
+	}
+	if err := g.LearnHyperparameters(rand.New(rand.NewSource(1)), false); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := g.SampleHyperparameters(rand.New(rand.NewSource(1)), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 5 {
+		t.Fatalf("len(samples) = %d; want 5", len(samples))
+	}
+	for _, theta := range samples {
+		if len(theta) != 2 {
+			t.Fatalf("len(theta) = %d; want 2", len(theta))
+		}
+		for _, v := range theta {
+			if v <= 0 {
+				t.Errorf("hyperparameter %f; want > 0", v)
+			}
+		}
+	}
+}
+
+func TestSampleHyperparametersUnsupportedCov(t *testing.T) {
+	t.Parallel()
+
+	g := New(MaternCov{}, 0)
+	g.Add([]float64{0}, 0)
+
+	if _, err := g.SampleHyperparameters(rand.New(rand.NewSource(1)), 5); err == nil {
+		t.Error("SampleHyperparameters() with non-ARD Cov; want error")
+	}
+}
+
+func TestLearnHyperparametersUnsupportedCov(t *testing.T) {
+	t.Parallel()
+
+	g := New(MaternCov{}, 0)
+	g.Add([]float64{0}, 0)
+
+	if err := g.LearnHyperparameters(rand.New(rand.NewSource(1)), false); err == nil {
+		t.Error("LearnHyperparameters() with non-ARD Cov; want error")
+	}
+}
+
+func TestLearnHyperparametersWithNoise(t *testing.T) {
+	t.Parallel()
+
+	g := New(MaternARD{
+		Lengthscales: []float64{1},
+		SignalVar:    1,
+	}, 1)
+	for _, x := range []float64{-10, -5, -1, 0, 1, 5, 10} {
+		g.Add([]float64{x}, math.Pow(x, 2))
+	}
+
+	if err := g.LearnHyperparameters(rand.New(rand.NewSource(1)), true); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.noise <= 0 {
+		t.Errorf("noise = %f; want > 0", g.noise)
+	}
+	if _, _, err := g.Estimate([]float64{0}); err != nil {
+		t.Fatalf("Estimate after LearnHyperparameters: %+v", err)
+	}
+}
@@ -125,7 +125,13 @@ outer:
 			upperPair = pairs[pairI+1]
 		}
 
-		mid := (xi - lowerPair.x[dim]) / (upperPair.x[dim] - lowerPair.x[dim])
+		// Repeated x values (e.g. an Optimizer stuck proposing the same
+		// bound-clamped candidate) make lowerPair and upperPair coincide;
+		// guard the division so that doesn't divide 0 by 0 into NaN.
+		var mid float64
+		if d := upperPair.x[dim] - lowerPair.x[dim]; d != 0 {
+			mid = (xi - lowerPair.x[dim]) / d
+		}
 		args := make([]float64, dims)
 		floats.AddScaled(args, 1-mid, lowerPair.x)
 		floats.AddScaled(args, mid, upperPair.x)
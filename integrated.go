@@ -0,0 +1,106 @@
+package bayesopt
+
+import (
+	"math/rand"
+
+	"github.com/pkg/errors"
+
+	"github.com/d4l3k/go-bayesopt/gp"
+	"github.com/d4l3k/go-bayesopt/quad"
+)
+
+// IntegratedAcquisition wraps a Base Exploration and averages its estimate
+// over uncertainty the base acquisition would otherwise ignore by
+// conditioning on a single point estimate. Pointwise acquisitions can be
+// misleading when observations are noisy or when kernel hyperparameters are
+// learned from few points (see GP.LearnHyperparameters); integrating them
+// out tends to produce better-calibrated candidates.
+//
+// Exactly one of QuadPoints or HyperSamples should be set, selecting which
+// of the two integration modes below is used:
+//
+//   - QuadPoints > 0 integrates the base acquisition over a 1-D nuisance
+//     dimension appended to x, ranging over [NuisanceMin, NuisanceMax],
+//     using fixed-order Gauss-Legendre quadrature (see quad.GaussLegendre).
+//     This models a GP trained with an extra input dimension representing
+//     the nuisance parameter (e.g. a noise level or averaged-out condition).
+//   - HyperSamples > 0 averages the base acquisition over that many GP
+//     hyperparameter samples drawn via GP.SampleHyperparameters, so the
+//     acquisition accounts for uncertainty in the kernel hyperparameters
+//     rather than conditioning on the single maximum-likelihood estimate.
+type IntegratedAcquisition struct {
+	Base Exploration
+
+	// NuisanceMin and NuisanceMax bound the nuisance dimension integrated
+	// over when QuadPoints is set.
+	NuisanceMin, NuisanceMax float64
+	// QuadPoints is the number of Gauss-Legendre quadrature points to use.
+	// If zero, hyperparameter-sample integration is used instead.
+	QuadPoints int
+
+	// HyperSamples is the number of GP hyperparameter samples to average
+	// over. Used only when QuadPoints is zero.
+	HyperSamples int
+
+	// Rand seeds the hyperparameter slice sampling used when HyperSamples is
+	// set. New fills this in with the Optimizer's own seeded rng (see
+	// WithRand) when it's nil, so it only needs to be set explicitly when
+	// using IntegratedAcquisition outside of an Optimizer.
+	Rand *rand.Rand
+}
+
+// Estimate implements Exploration.
+func (e IntegratedAcquisition) Estimate(g *gp.GP, minimize bool, x []float64) (float64, error) {
+	if e.QuadPoints > 0 {
+		return e.estimateQuadrature(g, minimize, x)
+	}
+	return e.estimateHyperSamples(g, minimize, x)
+}
+
+// estimateQuadrature integrates e.Base over the nuisance dimension appended
+// to x using Gauss-Legendre quadrature:
+// sum_i weights[i] * e.Base.Estimate(g, minimize, append(x, nodes[i])).
+func (e IntegratedAcquisition) estimateQuadrature(g *gp.GP, minimize bool, x []float64) (float64, error) {
+	nodes, weights := quad.GaussLegendre(e.NuisanceMin, e.NuisanceMax, e.QuadPoints)
+
+	xNode := append(append([]float64{}, x...), 0)
+	var sum float64
+	for i, node := range nodes {
+		xNode[len(x)] = node
+		v, err := e.Base.Estimate(g, minimize, xNode)
+		if err != nil {
+			return 0, err
+		}
+		sum += weights[i] * v
+	}
+	return sum, nil
+}
+
+// estimateHyperSamples averages e.Base over e.HyperSamples GP hyperparameter
+// samples, each evaluated against a copy of g's observations refit with
+// that sample's covariance.
+func (e IntegratedAcquisition) estimateHyperSamples(g *gp.GP, minimize bool, x []float64) (float64, error) {
+	ard, ok := g.Cov().(gp.ARDCov)
+	if !ok {
+		return 0, errors.Errorf("IntegratedAcquisition: cov %T does not implement ARDCov", g.Cov())
+	}
+	samples, err := g.SampleHyperparameters(e.Rand, e.HyperSamples)
+	if err != nil {
+		return 0, errors.Wrap(err, "IntegratedAcquisition")
+	}
+
+	inputs, outputs := g.RawData()
+	var sum float64
+	for _, theta := range samples {
+		sampled := gp.New(ard.WithHyperparameters(theta), g.Noise())
+		for i, in := range inputs {
+			sampled.Add(in, outputs[i])
+		}
+		v, err := e.Base.Estimate(sampled, minimize, x)
+		if err != nil {
+			return 0, err
+		}
+		sum += v
+	}
+	return sum / float64(len(samples)), nil
+}
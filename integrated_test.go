@@ -0,0 +1,156 @@
+package bayesopt
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/d4l3k/go-bayesopt/gp"
+)
+
+func TestIntegratedAcquisitionQuadrature(t *testing.T) {
+	t.Parallel()
+
+	// Train a GP over (x, sigma), where sigma is a nuisance "noise level"
+	// dimension rather than a parameter to search over.
+	rng := rand.New(rand.NewSource(1))
+	g := gp.New(gp.MaternCov{}, 1e-4)
+	for _, x := range []float64{0, 2, 4, 6, 8, 10} {
+		for _, sigma := range []float64{0, 1, 2} {
+			g.Add([]float64{x, sigma}, x+sigma*rng.NormFloat64())
+		}
+	}
+
+	integrated := IntegratedAcquisition{
+		Base:        ExpectedImprovement{},
+		NuisanceMin: 0,
+		NuisanceMax: 2,
+		QuadPoints:  5,
+	}
+
+	// x=1 is close to the known minimum (x=0), x=9 is far from it, so the
+	// integrated acquisition should favor exploring near x=1.
+	near, err := integrated.Estimate(g, true, []float64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	far, err := integrated.Estimate(g, true, []float64{9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if near <= far {
+		t.Errorf("Estimate(near) = %f; want > Estimate(far) = %f", near, far)
+	}
+}
+
+func TestIntegratedAcquisitionHyperSamples(t *testing.T) {
+	t.Parallel()
+
+	g := gp.New(gp.MaternARD{
+		Lengthscales: []float64{1},
+		SignalVar:    1,
+	}, 1e-4)
+	for _, x := range []float64{-10, -5, -1, 0, 1, 5, 10} {
+		g.Add([]float64{x}, math.Pow(x, 2))
+	}
+	if err := g.LearnHyperparameters(rand.New(rand.NewSource(1)), false); err != nil {
+		t.Fatal(err)
+	}
+
+	integrated := IntegratedAcquisition{
+		Base:         ExpectedImprovement{},
+		HyperSamples: 5,
+	}
+	near, err := integrated.Estimate(g, true, []float64{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if near <= 0 {
+		t.Errorf("Estimate(near) = %f; want > 0", near)
+	}
+}
+
+func TestIntegratedAcquisitionHyperSamplesUnsupportedCov(t *testing.T) {
+	t.Parallel()
+
+	g := gp.New(gp.MaternCov{}, 0)
+	g.Add([]float64{0}, 0)
+
+	integrated := IntegratedAcquisition{Base: ExpectedImprovement{}, HyperSamples: 5}
+	if _, err := integrated.Estimate(g, true, []float64{0}); err == nil {
+		t.Error("Estimate() with non-ARD cov; want error")
+	}
+}
+
+// TestIntegratedAcquisitionRegret checks the motivating case for
+// IntegratedAcquisition: pointwise EI conditioned on a single, poorly
+// chosen nuisance value can make bad exploration decisions, while
+// integrating over the nuisance range spanned by the training data is
+// robust to that choice.
+func TestIntegratedAcquisitionRegret(t *testing.T) {
+	t.Parallel()
+
+	candidates := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	const trueBest = 0.0 // f(x) = x, minimized at x = 0
+
+	// pointwiseSigma is a gross extrapolation well outside the training
+	// range [0, 4], which inflates the GP's uncertainty there and makes
+	// plain EI's ranking across x close to arbitrary.
+	const pointwiseSigma = 100.0
+	ei := ExpectedImprovement{}
+	pointwise := func(g *gp.GP, x float64) (float64, error) {
+		return ei.Estimate(g, true, []float64{x, pointwiseSigma})
+	}
+	integrated := IntegratedAcquisition{
+		Base:        ei,
+		NuisanceMin: 0,
+		NuisanceMax: 4,
+		QuadPoints:  5,
+	}
+	integratedEstimate := func(g *gp.GP, x float64) (float64, error) {
+		return integrated.Estimate(g, true, []float64{x})
+	}
+
+	regret := func(estimate func(g *gp.GP, x float64) (float64, error), seed int64) float64 {
+		rng := rand.New(rand.NewSource(seed))
+		g := gp.New(gp.MaternCov{}, 1e-4)
+		for _, x := range []float64{2, 5, 8} {
+			g.Add([]float64{x, rng.Float64() * 4}, x+2*rng.NormFloat64())
+		}
+
+		for round := 0; round < 3; round++ {
+			order := rng.Perm(len(candidates))
+			bestX, bestV := candidates[order[0]], math.Inf(-1)
+			for _, i := range order {
+				x := candidates[i]
+				v, err := estimate(g, x)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v > bestV {
+					bestX, bestV = x, v
+				}
+			}
+			g.Add([]float64{bestX, rng.Float64() * 4}, bestX+2*rng.NormFloat64())
+		}
+
+		// Regret is measured against the true (noise-free) objective at the
+		// best x found, since the best observed y can itself undershoot
+		// the true optimum due to noise.
+		bestX, _ := g.Minimum()
+		return bestX[0] - trueBest
+	}
+
+	const seeds = 20
+	var pointwiseTotal, integratedTotal float64
+	for s := int64(0); s < seeds; s++ {
+		pointwiseTotal += regret(pointwise, s)
+		integratedTotal += regret(integratedEstimate, s)
+	}
+
+	pointwiseAvg := pointwiseTotal / seeds
+	integratedAvg := integratedTotal / seeds
+	if integratedAvg > pointwiseAvg {
+		t.Errorf("average regret: integrated = %f; want <= pointwise = %f", integratedAvg, pointwiseAvg)
+	}
+}
@@ -0,0 +1,281 @@
+package bayesopt
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// InnerOptimizer maximizes an acquisition function over the bounds of
+// params. It's the gradient-free counterpart to the default selectCandidate
+// pipeline (random sampling + L-BFGS), set via WithInnerOptimizer.
+type InnerOptimizer interface {
+	// Maximize returns the point within params' bounds that maximizes f. rng
+	// is the Optimizer's seeded source (see WithRand); implementations must
+	// draw all of their randomness from it to keep WithRand reproducible.
+	Maximize(rng *rand.Rand, f func(x []float64) float64, params []Param) ([]float64, error)
+}
+
+var _ InnerOptimizer = NESOptimizer{}
+
+var (
+	// DefaultNESPopSize is the default population size per generation.
+	DefaultNESPopSize = 50
+	// DefaultNESLrMu is the default learning rate for the mean update.
+	DefaultNESLrMu = 1.0
+	// DefaultNESLrSigma is the default learning rate for the log-sigma
+	// update.
+	DefaultNESLrSigma = 0.1
+	// DefaultNESMomentum is the default momentum applied to both the mean
+	// and log-sigma updates.
+	DefaultNESMomentum = 0.9
+	// DefaultNESSigmaTol is the default convergence threshold: once every
+	// dimension's sigma drops below it, the search stops.
+	DefaultNESSigmaTol = 1e-3
+)
+
+// NESOptimizer maximizes an acquisition function using separable-Gaussian
+// Natural Evolution Strategies. It doesn't require gradients, so it copes
+// better with the multimodal acquisition surfaces a well-fit GP can produce
+// than the default gradient-based inner optimizer.
+//
+// Each generation draws a population of PopSize points around a per-
+// dimension mean/sigma, ranks them by f, and nudges the mean towards the
+// better half while shrinking or growing sigma depending on whether the
+// population agrees on a direction. The search stops once every dimension's
+// sigma falls below SigmaTol, or after a generation budget that scales with
+// the dimensionality. See ESOptimizer for a variant with a different sigma
+// gradient estimator and Nesterov momentum.
+type NESOptimizer struct {
+	// PopSize is the number of points sampled per generation. Defaults to
+	// DefaultNESPopSize if zero.
+	PopSize int
+	// LrMu is the learning rate for the mean update. Defaults to
+	// DefaultNESLrMu if zero.
+	LrMu float64
+	// LrSigma is the learning rate for the log-sigma update. Defaults to
+	// DefaultNESLrSigma if zero.
+	LrSigma float64
+	// Momentum is applied to both the mean and log-sigma updates. Defaults
+	// to DefaultNESMomentum if zero.
+	Momentum float64
+	// SigmaTol is the per-dimension sigma value below which the search is
+	// considered converged. Defaults to DefaultNESSigmaTol if zero.
+	SigmaTol float64
+}
+
+// Maximize implements InnerOptimizer.
+func (n NESOptimizer) Maximize(rng *rand.Rand, f func(x []float64) float64, params []Param) ([]float64, error) {
+	popSize := n.PopSize
+	if popSize < 1 {
+		popSize = DefaultNESPopSize
+	}
+	lrMu := n.LrMu
+	if lrMu == 0 {
+		lrMu = DefaultNESLrMu
+	}
+	lrSigma := n.LrSigma
+	if lrSigma == 0 {
+		lrSigma = DefaultNESLrSigma
+	}
+	momentum := n.Momentum
+	if momentum == 0 {
+		momentum = DefaultNESMomentum
+	}
+	sigmaTol := n.SigmaTol
+	if sigmaTol == 0 {
+		sigmaTol = DefaultNESSigmaTol
+	}
+
+	return evolutionStrategy{
+		popSize:   popSize,
+		lrMu:      lrMu,
+		lrSigma:   lrSigma,
+		momentum:  momentum,
+		sigmaTol:  sigmaTol,
+		sigmaGrad: func(z float64) float64 { return z*z - 1 },
+	}.maximize(rng, f, params)
+}
+
+// nesSample is one member of a generation's population: z is the standard
+// normal draw, x is its bounded mu + sigma*z point, and y is f(x).
+type nesSample struct {
+	z []float64
+	x []float64
+	y float64
+}
+
+// evolutionStrategy is the separable-Gaussian evolution strategy loop shared
+// by NESOptimizer and ESOptimizer: they sample, rank, and update a
+// per-dimension mean/sigma identically, differing only in whether the
+// sampling point is Nesterov-extrapolated, how the sigma gradient is
+// estimated from each sample's z, and whether the result is the final mean
+// or the best point any generation actually evaluated.
+type evolutionStrategy struct {
+	popSize  int
+	lrMu     float64
+	lrSigma  float64
+	momentum float64
+	sigmaTol float64
+	// nesterov samples each generation around the momentum-extrapolated
+	// mean/sigma rather than the current one.
+	nesterov bool
+	// sigmaGrad maps a population member's standard normal draw z to its
+	// contribution to the log-sigma gradient.
+	sigmaGrad func(z float64) float64
+	// trackBest returns the best point any generation evaluated instead of
+	// the final mean, which matters when the mean drifts back off a narrow
+	// peak late in the search.
+	trackBest bool
+}
+
+func (e evolutionStrategy) maximize(rng *rand.Rand, f func(x []float64) float64, params []Param) ([]float64, error) {
+	dim := paramDims(params)
+	min, max := paramBounds(params)
+
+	mu := make([]float64, dim)
+	logSigma := make([]float64, dim)
+	for i := 0; i < dim; i++ {
+		mu[i] = (min[i] + max[i]) / 2
+		rng := max[i] - min[i]
+		if rng <= 0 {
+			rng = 1
+		}
+		logSigma[i] = math.Log(rng / 4)
+	}
+
+	muVelocity := make([]float64, dim)
+	sigmaVelocity := make([]float64, dim)
+
+	bestX := append([]float64{}, mu...)
+	bestY := math.Inf(-1)
+
+	maxGen := int(math.Ceil(math.Sqrt(2*float64(dim)+1) * 300))
+
+	for gen := 0; gen < maxGen; gen++ {
+		maxSigma := 0.0
+		for i := 0; i < dim; i++ {
+			if s := math.Exp(logSigma[i]); s > maxSigma {
+				maxSigma = s
+			}
+		}
+		if maxSigma < e.sigmaTol {
+			break
+		}
+
+		// Nesterov samples the generation around the lookahead point so the
+		// gradient estimate accounts for the step momentum is already
+		// carrying the search through; plain NES samples around the current
+		// mean/sigma.
+		sampleMu := mu
+		sampleSigma := make([]float64, dim)
+		if e.nesterov {
+			sampleMu = make([]float64, dim)
+			for i := 0; i < dim; i++ {
+				sampleMu[i] = mu[i] + e.momentum*muVelocity[i]
+				sampleSigma[i] = math.Exp(logSigma[i] + e.momentum*sigmaVelocity[i])
+			}
+		} else {
+			for i := 0; i < dim; i++ {
+				sampleSigma[i] = math.Exp(logSigma[i])
+			}
+		}
+
+		pop := make([]nesSample, e.popSize)
+		for k := range pop {
+			z, x := sampleWithinBounds(rng, sampleMu, sampleSigma, min, max)
+			pop[k] = nesSample{z: z, x: x, y: f(x)}
+			if e.trackBest && pop[k].y > bestY {
+				bestY = pop[k].y
+				bestX = append([]float64{}, x...)
+			}
+		}
+		sort.Slice(pop, func(a, b int) bool {
+			return pop[a].y > pop[b].y
+		})
+
+		weights := nesWeights(e.popSize)
+
+		muGrad := make([]float64, dim)
+		sigmaGrad := make([]float64, dim)
+		for k, s := range pop {
+			w := weights[k]
+			if w == 0 {
+				continue
+			}
+			for i := 0; i < dim; i++ {
+				muGrad[i] += w * s.z[i]
+				sigmaGrad[i] += w * e.sigmaGrad(s.z[i])
+			}
+		}
+
+		for i := 0; i < dim; i++ {
+			muVelocity[i] = e.momentum*muVelocity[i] + e.lrMu*sampleSigma[i]*muGrad[i]
+			mu[i] += muVelocity[i]
+			if mu[i] < min[i] {
+				mu[i] = min[i]
+			} else if mu[i] > max[i] {
+				mu[i] = max[i]
+			}
+
+			sigmaVelocity[i] = e.momentum*sigmaVelocity[i] + e.lrSigma*sigmaGrad[i]
+			logSigma[i] += sigmaVelocity[i]
+		}
+	}
+
+	if e.trackBest {
+		return bestX, nil
+	}
+	return mu, nil
+}
+
+// nesWeights returns the utility weights used to combine a ranked
+// population: the top half get weight proportional to their rank,
+// normalized to sum to 1, and the bottom half get zero weight.
+func nesWeights(popSize int) []float64 {
+	weights := make([]float64, popSize)
+	half := popSize / 2
+	var sum float64
+	for k := 0; k < half; k++ {
+		w := float64(half - k)
+		weights[k] = w
+		sum += w
+	}
+	if sum > 0 {
+		for k := range weights[:half] {
+			weights[k] /= sum
+		}
+	}
+	return weights
+}
+
+// sampleWithinBounds draws z ~ N(0, I) from rng and x = mu + sigma*z,
+// accepting the first draw that falls within [min, max] and otherwise
+// clamping the last attempt, mirroring truncateSample's
+// accept-reject-then-clamp behavior.
+func sampleWithinBounds(rng *rand.Rand, mu, sigma, min, max []float64) (z, x []float64) {
+	dim := len(mu)
+	z = make([]float64, dim)
+	x = make([]float64, dim)
+	for try := 0; try < SampleTries; try++ {
+		inBounds := true
+		for i := 0; i < dim; i++ {
+			z[i] = rng.NormFloat64()
+			x[i] = mu[i] + sigma[i]*z[i]
+			if x[i] < min[i] || x[i] > max[i] {
+				inBounds = false
+			}
+		}
+		if inBounds {
+			return z, x
+		}
+	}
+	for i := range x {
+		if x[i] < min[i] {
+			x[i] = min[i]
+		} else if x[i] > max[i] {
+			x[i] = max[i]
+		}
+	}
+	return z, x
+}
@@ -0,0 +1,90 @@
+package bayesopt
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/floats/scalar"
+)
+
+func TestNESOptimizerMaximize(t *testing.T) {
+	t.Parallel()
+
+	n := NESOptimizer{}
+	x := UniformParam{Name: "x", Max: 10, Min: -10}
+	y := UniformParam{Name: "y", Max: 10, Min: -10}
+	params := []Param{x, y}
+
+	// Maximize the negative squared distance to (3, -4), i.e. find its peak.
+	target := []float64{3, -4}
+	f := func(v []float64) float64 {
+		return -math.Pow(v[0]-target[0], 2) - math.Pow(v[1]-target[1], 2)
+	}
+
+	got, err := n.Maximize(rand.New(rand.NewSource(1)), f, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !floats.EqualApprox(got, target, 0.5) {
+		t.Errorf("Maximize() = %+v; want close to %+v", got, target)
+	}
+}
+
+func TestNESOptimizerBounds(t *testing.T) {
+	t.Parallel()
+
+	n := NESOptimizer{}
+	x := UniformParam{Name: "x", Max: 10, Min: 5}
+	params := []Param{x}
+
+	// The unconstrained maximum (x = 0) lies outside [5, 10]; the optimizer
+	// should settle near the boundary instead.
+	f := func(v []float64) float64 {
+		return -math.Pow(v[0], 2)
+	}
+
+	got, err := n.Maximize(rand.New(rand.NewSource(1)), f, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] < 5 || got[0] > 10 {
+		t.Errorf("Maximize() = %+v; want within [5, 10]", got)
+	}
+}
+
+func TestOptimizerWithInnerOptimizer(t *testing.T) {
+	t.Parallel()
+
+	X := LinearParam{
+		Max: 10,
+		Min: -10,
+	}
+	o := New(
+		[]Param{X},
+		WithInnerOptimizer(NESOptimizer{}),
+		WithRounds(20),
+	)
+	x, y, err := o.Optimize(func(params map[Param]float64) float64 {
+		return math.Pow(params[X], 2) + 1
+	})
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+
+	{
+		got := x[X]
+		want := 0.0
+		if !scalar.EqualWithinAbs(got, want, 1) {
+			t.Errorf("got x = %f; not %f", got, want)
+		}
+	}
+	{
+		got := y
+		want := 1.0
+		if !scalar.EqualWithinAbs(got, want, 1) {
+			t.Errorf("got y = %f; not %f", got, want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package bayesopt
 import (
 	"math"
 	"math/rand"
+
+	"gonum.org/v1/gonum/floats"
 )
 
 // SampleTries is the number of tries a sample function should try before
@@ -22,7 +24,29 @@ type Param interface {
 	Sample() float64
 }
 
+// RandSampler is implemented by Params that can draw from an explicit
+// *rand.Rand instead of the global math/rand source. WithRand uses this to
+// make an Optimizer's proposals bit-for-bit reproducible for a given seed;
+// Params that don't implement it fall back to Sample, trading away
+// reproducibility for that param specifically.
+type RandSampler interface {
+	// SampleFrom returns a random point within the bounds using rng. It
+	// doesn't have to be uniformly distributed.
+	SampleFrom(rng *rand.Rand) float64
+}
+
+// sample returns p's next random value, preferring rng via RandSampler when p
+// implements it and otherwise falling back to the global math/rand source via
+// Sample.
+func sample(p Param, rng *rand.Rand) float64 {
+	if rs, ok := p.(RandSampler); ok {
+		return rs.SampleFrom(rng)
+	}
+	return p.Sample()
+}
+
 var _ Param = UniformParam{}
+var _ RandSampler = UniformParam{}
 
 // LinearParam is a UniformParam. Deprecated.
 type LinearParam = UniformParam
@@ -53,7 +77,13 @@ func (p UniformParam) Sample() float64 {
 	return rand.Float64()*(p.Max-p.Min) + p.Min
 }
 
+// SampleFrom implements RandSampler.
+func (p UniformParam) SampleFrom(rng *rand.Rand) float64 {
+	return rng.Float64()*(p.Max-p.Min) + p.Min
+}
+
 var _ Param = NormalParam{}
+var _ RandSampler = NormalParam{}
 
 // NormalParam is a normally distributed parameter with Mean and StdDev.
 // The Max and Min parameters use discard sampling to find a point between them.
@@ -86,7 +116,15 @@ func (p NormalParam) Sample() float64 {
 	})
 }
 
+// SampleFrom implements RandSampler.
+func (p NormalParam) SampleFrom(rng *rand.Rand) float64 {
+	return truncateSampleFrom(p, rng, func(rng *rand.Rand) float64 {
+		return rng.NormFloat64()*p.StdDev + p.Mean
+	})
+}
+
 var _ Param = ExponentialParam{}
+var _ RandSampler = ExponentialParam{}
 
 // ExponentialParam is an exponentially distributed parameter between 0 and in
 // the range (0, +math.MaxFloat64] whose rate parameter (lambda) is Rate and
@@ -121,6 +159,13 @@ func (p ExponentialParam) Sample() float64 {
 	})
 }
 
+// SampleFrom implements RandSampler.
+func (p ExponentialParam) SampleFrom(rng *rand.Rand) float64 {
+	return truncateSampleFrom(p, rng, func(rng *rand.Rand) float64 {
+		return rng.ExpFloat64() / p.Rate
+	})
+}
+
 func truncateSample(p Param, f func() float64) float64 {
 	max := p.GetMax()
 	min := p.GetMin()
@@ -135,6 +180,228 @@ func truncateSample(p Param, f func() float64) float64 {
 	return math.Min(math.Max(sample, min), max)
 }
 
+// truncateSampleFrom is the RandSampler counterpart to truncateSample,
+// drawing from rng instead of the global math/rand source.
+func truncateSampleFrom(p Param, rng *rand.Rand, f func(*rand.Rand) float64) float64 {
+	max := p.GetMax()
+	min := p.GetMin()
+
+	var sample float64
+	for i := 0; i < SampleTries; i++ {
+		sample = f(rng)
+		if sample >= min && sample <= max {
+			return sample
+		}
+	}
+	return math.Min(math.Max(sample, min), max)
+}
+
+var _ Param = IntParam{}
+var _ RandSampler = IntParam{}
+
+// Rounder is implemented by parameters whose continuous proposals need to be
+// snapped to a discrete grid, such as IntParam, before being handed to the
+// objective function or logged against the GP. Acquisition optimization
+// itself still runs on the continuous relaxation of the parameter.
+type Rounder interface {
+	Round(v float64) float64
+}
+
+var _ Rounder = IntParam{}
+
+// IntParam is a uniformly distributed integer parameter between Max and Min
+// (inclusive). The GP still models it as a continuous dimension; proposed
+// values are rounded to the nearest integer via Round before being returned
+// from Optimize or Next.
+type IntParam struct {
+	Name     string
+	Max, Min int
+}
+
+// GetName implements Param.
+func (p IntParam) GetName() string {
+	return p.Name
+}
+
+// GetMax implements Param.
+func (p IntParam) GetMax() float64 {
+	return float64(p.Max)
+}
+
+// GetMin implements Param.
+func (p IntParam) GetMin() float64 {
+	return float64(p.Min)
+}
+
+// Sample implements Param.
+func (p IntParam) Sample() float64 {
+	return float64(p.Min + rand.Intn(p.Max-p.Min+1))
+}
+
+// SampleFrom implements RandSampler.
+func (p IntParam) SampleFrom(rng *rand.Rand) float64 {
+	return float64(p.Min + rng.Intn(p.Max-p.Min+1))
+}
+
+// Round implements Rounder.
+func (p IntParam) Round(v float64) float64 {
+	return math.Round(v)
+}
+
+// MultiDimParam is implemented by parameters that expand into more than one
+// GP input dimension, such as a one-hot encoded CategoricalParam. Params
+// that don't implement it occupy exactly one dimension.
+type MultiDimParam interface {
+	Param
+
+	// Dims returns the number of GP input dimensions this parameter expands
+	// into.
+	Dims() int
+	// Encode converts the parameter's public value (as returned by Sample and
+	// reported in Optimize's results) into its GP input encoding.
+	Encode(v float64) []float64
+	// Decode converts a raw GP input encoding back to the parameter's public
+	// value.
+	Decode(x []float64) float64
+}
+
+var _ Param = (*CategoricalParam)(nil)
+var _ MultiDimParam = (*CategoricalParam)(nil)
+var _ Rounder = (*CategoricalParam)(nil)
+var _ RandSampler = (*CategoricalParam)(nil)
+
+// CategoricalParam picks between a fixed set of string Choices. It's one-hot
+// encoded into len(Choices) GP input dimensions, so the Matérn kernel sees a
+// distance between choices instead of a meaningless linear ordering of
+// indices. Externally it behaves like an IntParam over the index into
+// Choices: Sample and Optimize's results give the chosen index, and Choice
+// recovers the string.
+//
+// Unlike the other Param implementations, CategoricalParam must be used by
+// pointer (e.g. &CategoricalParam{...}): Param values are used as keys in
+// the optimizer's result maps, and a Choices slice would make the struct
+// unusable as a map key.
+type CategoricalParam struct {
+	Name    string
+	Choices []string
+}
+
+// GetName implements Param.
+func (p *CategoricalParam) GetName() string {
+	return p.Name
+}
+
+// GetMax implements Param.
+func (p *CategoricalParam) GetMax() float64 {
+	return float64(len(p.Choices) - 1)
+}
+
+// GetMin implements Param.
+func (p *CategoricalParam) GetMin() float64 {
+	return 0
+}
+
+// Sample implements Param.
+func (p *CategoricalParam) Sample() float64 {
+	return float64(rand.Intn(len(p.Choices)))
+}
+
+// SampleFrom implements RandSampler.
+func (p *CategoricalParam) SampleFrom(rng *rand.Rand) float64 {
+	return float64(rng.Intn(len(p.Choices)))
+}
+
+// Round implements Rounder.
+func (p *CategoricalParam) Round(v float64) float64 {
+	return math.Round(v)
+}
+
+// Choice returns the choice selected by index, as returned in the optimizer's
+// map[Param]float64 results.
+func (p *CategoricalParam) Choice(index float64) string {
+	return p.Choices[int(math.Round(index))]
+}
+
+// Dims implements MultiDimParam.
+func (p *CategoricalParam) Dims() int {
+	return len(p.Choices)
+}
+
+// Encode implements MultiDimParam, one-hot encoding the chosen index.
+func (p *CategoricalParam) Encode(v float64) []float64 {
+	oneHot := make([]float64, len(p.Choices))
+	oneHot[int(math.Round(v))] = 1
+	return oneHot
+}
+
+// Decode implements MultiDimParam, picking the index of the largest one-hot
+// component.
+func (p *CategoricalParam) Decode(x []float64) float64 {
+	return float64(floats.MaxIdx(x))
+}
+
+// ConditionalParam wraps another Param so that it's only meaningful when
+// Predicate holds for the current value of Parent, e.g. only sampling the
+// i-th layer's width if num_layers >= i. Parent must be sampled before the
+// ConditionalParam in the optimizer's parameter list. When inactive, Default
+// is used in place of Param's sampled value, both in the value handed to the
+// objective function and in the GP inputs, so the optimizer doesn't have to
+// explain variance in a dimension that isn't actually in play.
+//
+// Like CategoricalParam, ConditionalParam must be used by pointer (e.g.
+// &ConditionalParam{...}): its Predicate func field would otherwise make it
+// unusable as a map key.
+type ConditionalParam struct {
+	Param
+
+	Parent    Param
+	Predicate func(parentValue float64) bool
+	Default   float64
+}
+
+// Active reports whether this parameter is in play given the value sampled
+// or proposed for Parent.
+func (p *ConditionalParam) Active(parentValue float64) bool {
+	return p.Predicate(parentValue)
+}
+
+// SampleFrom implements RandSampler, deferring to the wrapped Param.
+func (p *ConditionalParam) SampleFrom(rng *rand.Rand) float64 {
+	return sample(p.Param, rng)
+}
+
+// paramDims returns the number of GP input dimensions used to encode params,
+// expanding one-hot encoded MultiDimParams and counting everything else as a
+// single dimension.
+func paramDims(params []Param) int {
+	n := 0
+	for _, p := range params {
+		if md, ok := p.(MultiDimParam); ok {
+			n += md.Dims()
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// paramBounds returns the per-GP-input-dimension (min, max) bounds for
+// params, expanding one-hot encoded dimensions to (0, 1).
+func paramBounds(params []Param) (min, max []float64) {
+	for _, p := range params {
+		if md, ok := p.(MultiDimParam); ok {
+			for i := 0; i < md.Dims(); i++ {
+				min = append(min, 0)
+				max = append(max, 1)
+			}
+			continue
+		}
+		min = append(min, p.GetMin())
+		max = append(max, p.GetMax())
+	}
+	return min, max
+}
+
 // RejectionParam samples from Param and then uses F to decide whether or not to
 // reject the sample. This is typically used with a UniformParam. F should
 // output a value between 0 and 1 indicating the proportion of samples this
@@ -156,3 +423,14 @@ func (p RejectionParam) Sample() float64 {
 		}
 	}
 }
+
+// SampleFrom implements RandSampler.
+func (p RejectionParam) SampleFrom(rng *rand.Rand) float64 {
+	for {
+		x := sample(p.Param, rng)
+		y := p.F(x)
+		if rng.Float64() < y {
+			return x
+		}
+	}
+}
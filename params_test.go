@@ -44,6 +44,25 @@ func TestParams(t *testing.T) {
 			max:  10,
 			min:  0,
 		},
+		{
+			p: IntParam{
+				Name: "int",
+				Max:  10,
+				Min:  1,
+			},
+			name: "int",
+			max:  10,
+			min:  1,
+		},
+		{
+			p: &CategoricalParam{
+				Name:    "categorical",
+				Choices: []string{"a", "b", "c"},
+			},
+			name: "categorical",
+			max:  2,
+			min:  0,
+		},
 	}
 
 	for i, c := range cases {
@@ -77,6 +96,75 @@ func TestParams(t *testing.T) {
 	}
 }
 
+func TestIntParamRound(t *testing.T) {
+	t.Parallel()
+
+	p := IntParam{Name: "int", Max: 10, Min: 0}
+	cases := []struct {
+		in   float64
+		want float64
+	}{
+		{in: 3.2, want: 3},
+		{in: 3.6, want: 4},
+		{in: -0.4, want: 0},
+	}
+	for i, c := range cases {
+		if out := p.Round(c.in); out != c.want {
+			t.Errorf("%d. IntParam.Round(%v) = %v; want %v", i, c.in, out, c.want)
+		}
+	}
+}
+
+func TestCategoricalParam(t *testing.T) {
+	t.Parallel()
+
+	p := &CategoricalParam{Name: "categorical", Choices: []string{"a", "b", "c"}}
+
+	if got, want := p.Dims(), 3; got != want {
+		t.Errorf("Dims() = %d; want %d", got, want)
+	}
+	if got, want := p.Choice(1), "b"; got != want {
+		t.Errorf("Choice(1) = %q; want %q", got, want)
+	}
+	if got, want := p.Encode(1), ([]float64{0, 1, 0}); !floatsEqual(got, want) {
+		t.Errorf("Encode(1) = %v; want %v", got, want)
+	}
+	if got, want := p.Decode([]float64{0.1, 0.7, 0.2}), 1.0; got != want {
+		t.Errorf("Decode([0.1, 0.7, 0.2]) = %v; want %v", got, want)
+	}
+}
+
+func floatsEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestConditionalParam(t *testing.T) {
+	t.Parallel()
+
+	numLayers := UniformParam{Name: "num_layers", Max: 3, Min: 1}
+	p := &ConditionalParam{
+		Param:     UniformParam{Name: "layer2_width", Max: 100, Min: 1},
+		Parent:    numLayers,
+		Predicate: func(parentValue float64) bool { return parentValue >= 2 },
+		Default:   0,
+	}
+
+	if p.Active(1) {
+		t.Errorf("Active(1) = true; want false")
+	}
+	if !p.Active(2) {
+		t.Errorf("Active(2) = false; want true")
+	}
+}
+
 func TestTruncateSample(t *testing.T) {
 	t.Parallel()
 
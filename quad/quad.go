@@ -0,0 +1,54 @@
+// Package quad provides fixed-order numerical quadrature rules, used to
+// integrate acquisition functions over a nuisance dimension instead of
+// evaluating them pointwise.
+package quad
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// GaussLegendre returns the n-point Gauss-Legendre quadrature nodes and
+// weights for integrating a function over [min, max]: the integral is
+// approximated as sum_i weights[i] * f(nodes[i]).
+//
+// The nodes and weights are computed via the Golub-Welsch algorithm: the
+// nodes on [-1, 1] are the eigenvalues of the symmetric tridiagonal Jacobi
+// matrix for the Legendre three-term recurrence, and the corresponding
+// weights are derived from the first component of each eigenvector. The
+// result is then rescaled from [-1, 1] to [min, max].
+func GaussLegendre(min, max float64, n int) (nodes, weights []float64) {
+	if n < 1 {
+		return nil, nil
+	}
+	if n == 1 {
+		return []float64{0.5 * (min + max)}, []float64{max - min}
+	}
+
+	jacobi := mat.NewSymDense(n, nil)
+	for i := 1; i < n; i++ {
+		fi := float64(i)
+		b := fi / math.Sqrt(4*fi*fi-1)
+		jacobi.SetSym(i-1, i, b)
+	}
+
+	var eig mat.EigenSym
+	if ok := eig.Factorize(jacobi, true); !ok {
+		return nil, nil
+	}
+	values := eig.Values(nil)
+	var vectors mat.Dense
+	eig.VectorsTo(&vectors)
+
+	nodes = make([]float64, n)
+	weights = make([]float64, n)
+	for i, t := range values {
+		v0 := vectors.At(0, i)
+		w := 2 * v0 * v0
+
+		nodes[i] = 0.5*(max-min)*t + 0.5*(max+min)
+		weights[i] = w * 0.5 * (max - min)
+	}
+	return nodes, weights
+}
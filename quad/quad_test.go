@@ -0,0 +1,55 @@
+package quad
+
+import (
+	"math"
+	"testing"
+)
+
+// integrate approximates the integral of f over [min, max] using an n-point
+// Gauss-Legendre rule.
+func integrate(f func(float64) float64, min, max float64, n int) float64 {
+	nodes, weights := GaussLegendre(min, max, n)
+	var sum float64
+	for i, x := range nodes {
+		sum += weights[i] * f(x)
+	}
+	return sum
+}
+
+func TestGaussLegendreWeightsSumToRange(t *testing.T) {
+	t.Parallel()
+
+	_, weights := GaussLegendre(-2, 3, 5)
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	if math.Abs(sum-5) > 1e-9 {
+		t.Errorf("sum(weights) = %f; want 5", sum)
+	}
+}
+
+func TestGaussLegendreExactForPolynomials(t *testing.T) {
+	t.Parallel()
+
+	// An n-point rule integrates polynomials up to degree 2n-1 exactly, so
+	// a 3-point rule should be exact for x^2 and x^4 on [-1, 1].
+	got := integrate(func(x float64) float64 { return x * x }, -1, 1, 3)
+	if want := 2.0 / 3; math.Abs(got-want) > 1e-9 {
+		t.Errorf("integrate(x^2) = %f; want %f", got, want)
+	}
+
+	got = integrate(func(x float64) float64 { return x * x * x * x }, -1, 1, 3)
+	if want := 2.0 / 5; math.Abs(got-want) > 1e-9 {
+		t.Errorf("integrate(x^4) = %f; want %f", got, want)
+	}
+}
+
+func TestGaussLegendreRescalesInterval(t *testing.T) {
+	t.Parallel()
+
+	got := integrate(func(x float64) float64 { return x }, 0, 10, 4)
+	if want := 50.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("integrate(x, [0, 10]) = %f; want %f", got, want)
+	}
+}